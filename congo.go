@@ -1,10 +1,16 @@
 package congo
 
 import (
+	"context"
+	"encoding"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,10 +39,94 @@ type Source interface {
 
 // Setting is a value that is part of the configuration of a system.
 type Setting struct {
-	Name     string // name the key of the setting
-	Usage    string // contains information on how to use the setting
-	Value    Value  // value as set
-	DefValue string // default value (as text)
+	Name     string  // name the key of the setting
+	Usage    string  // contains information on how to use the setting
+	Value    Value   // value as set
+	DefValue string  // default value (as text)
+	Origin   *Origin // origin of the value; nil if the default value is used
+	Required bool    // whether a source must set the setting
+	Secret   bool    // whether the value must not be disclosed
+	Rules    []Rule  // rules the value must follow
+}
+
+// Help returns the usage of the setting followed by a description of the
+// constraints of its value e.g. "Port to listen on (required, min=1)".
+func (s *Setting) Help() string {
+	var constraints []string
+	if s.Required {
+		constraints = append(constraints, "required")
+	}
+	for _, rule := range s.Rules {
+		constraints = append(constraints, rule.String())
+	}
+	if len(constraints) == 0 {
+		return s.Usage
+	}
+	help := "(" + strings.Join(constraints, ", ") + ")"
+	if s.Usage == "" {
+		return help
+	}
+	return s.Usage + " " + help
+}
+
+// Set sets the value of the setting from its string representation and
+// records the origin of the value. Sources should use Set instead of setting
+// the Value directly, so it can be traced where the value came from.
+//
+// If the value can't be set a *LoadError is returned.
+func (s *Setting) Set(raw string, origin Origin) error {
+	if err := s.Value.Set(raw); err != nil {
+		return &LoadError{s.Name, origin.Source, origin.Location, raw, err}
+	}
+	origin.Raw = raw
+	s.Origin = &origin
+	return nil
+}
+
+// Append appends to the value of the setting if it holds several elements
+// (see SliceValue) and records the origin of the appended value.
+// All other values are set as by Set.
+func (s *Setting) Append(raw string, origin Origin) error {
+	slice, ok := s.Value.(SliceValue)
+	if !ok {
+		return s.Set(raw, origin)
+	}
+	if err := slice.Append(raw); err != nil {
+		return &LoadError{s.Name, origin.Source, origin.Location, raw, err}
+	}
+	origin.Raw = raw
+	s.Origin = &origin
+	return nil
+}
+
+// Names returns the names of given settings in sorted order.
+// Sources can use it to process settings in a deterministic order.
+func Names(settings map[string]*Setting) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultSource is the source name of the origin of settings
+// that weren't set by any source.
+const DefaultSource = "default"
+
+// Origin describes where the value of a setting came from.
+type Origin struct {
+	Source   string // name of the source e.g. "flag", "env" or "ini"
+	Raw      string // value as given by the source
+	Location string // location in the source e.g. a file and line, a variable or a flag name
+}
+
+// String returns a human readable description of the origin.
+func (o Origin) String() string {
+	if o.Location == "" {
+		return o.Source
+	}
+	return o.Source + " (" + o.Location + ")"
 }
 
 // New creates a new configuration that uses given sources to resolve
@@ -45,89 +135,156 @@ type Setting struct {
 // provides by sources in the back.
 func New(name string, sources ...Source) Congo {
 	return &congo{
-		sources,
-		make(map[string]*Setting),
-		name,
-		os.Stderr,
+		sources:  sources,
+		settings: make(map[string]*Setting),
+		name:     name,
+		output:   os.Stderr,
 	}
 }
 
 // Congo is a configuration capable of loading settings from different
 // sources.
+//
+// All methods defining settings accept Options (e.g. Required()) that
+// further describe the setting.
+//
+// Settings must be defined before the configuration is used concurrently.
+// Afterwards all methods are safe for concurrent use. See Read() and Snapshot()
+// on how to access the values of settings while reloading.
 type Congo interface {
 	// BoolVar defines a bool setting with specified name, default value, and usage string.
 	// The argument p points to a bool variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	BoolVar(p *bool, name string, value bool, usage string) Congo
+	BoolVar(p *bool, name string, value bool, usage string, opts ...Option) Congo
 	// Bool defines a bool setting with specified name, default value, and usage string.
 	// The return value is the address of a bool variable that stores the value of the setting.
-	Bool(name string, value bool, usage string) *bool
+	Bool(name string, value bool, usage string, opts ...Option) *bool
 
 	// IntVar defines an int setting with specified name, default value, and usage string.
 	// The argument p points to an int variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	IntVar(p *int, name string, value int, usage string) Congo
+	IntVar(p *int, name string, value int, usage string, opts ...Option) Congo
 	// Int defines an int setting with specified name, default value, and usage string.
 	// The return value is the address of an int variable that stores the value of the setting.
-	Int(name string, value int, usage string) *int
+	Int(name string, value int, usage string, opts ...Option) *int
 
 	// Int64Var defines an int64 setting with specified name, default value, and usage string.
 	// The argument p points to an int64 variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	Int64Var(p *int64, name string, value int64, usage string) Congo
+	Int64Var(p *int64, name string, value int64, usage string, opts ...Option) Congo
 	// Int64 defines an int64 setting with specified name, default value, and usage string.
 	// The return value is the address of an int64 variable that stores the value of the setting.
-	Int64(name string, value int64, usage string) *int64
+	Int64(name string, value int64, usage string, opts ...Option) *int64
 
 	// UintVar defines a uint setting with specified name, default value, and usage string.
 	// The argument p points to a uint variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	UintVar(p *uint, name string, value uint, usage string) Congo
+	UintVar(p *uint, name string, value uint, usage string, opts ...Option) Congo
 	// Uint defines a uint setting with specified name, default value, and usage string.
 	// The return value is the address of a uint variable that stores the value of the setting.
-	Uint(name string, value uint, usage string) *uint
+	Uint(name string, value uint, usage string, opts ...Option) *uint
 
 	// Uint64Var defines a uint64 setting with specified name, default value, and usage string.
 	// The argument p points to a uint64 variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	Uint64Var(p *uint64, name string, value uint64, usage string) Congo
+	Uint64Var(p *uint64, name string, value uint64, usage string, opts ...Option) Congo
 	// Uint64 defines a uint64 setting with specified name, default value, and usage string.
 	// The return value is the address of a uint64 variable that stores the value of the setting.
-	Uint64(name string, value uint64, usage string) *uint64
+	Uint64(name string, value uint64, usage string, opts ...Option) *uint64
 
 	// StringVar defines a string setting with specified name, default value, and usage string.
 	// The argument p points to a string variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	StringVar(p *string, name string, value string, usage string) Congo
+	StringVar(p *string, name string, value string, usage string, opts ...Option) Congo
 	// String defines a string setting with specified name, default value, and usage string.
 	// The return value is the address of a string variable that stores the value of the setting.
-	String(name string, value string, usage string) *string
+	String(name string, value string, usage string, opts ...Option) *string
 
 	// Float64Var defines a float64 setting with specified name, default value, and usage string.
 	// The argument p points to a float64 variable in which to store the value of the setting.
 	//
 	// Returns itself so calls can be chained.
-	Float64Var(p *float64, name string, value float64, usage string) Congo
+	Float64Var(p *float64, name string, value float64, usage string, opts ...Option) Congo
 	// Float64 defines a float64 setting with specified name, default value, and usage string.
 	// The return value is the address of a float64 variable that stores the value of the setting.
-	Float64(name string, value float64, usage string) *float64
+	Float64(name string, value float64, usage string, opts ...Option) *float64
 
 	// DurationVar defines a time.Duration setting with specified name, default value, and usage string.
 	// The argument p points to a time.Duration variable in which to store the value of the setting.
 	// The setting accepts a value acceptable to time.ParseDuration.
 	//
 	// Returns itself so calls can be chained.
-	DurationVar(p *time.Duration, name string, value time.Duration, usage string) Congo
+	DurationVar(p *time.Duration, name string, value time.Duration, usage string, opts ...Option) Congo
 	// Duration defines a time.Duration setting with specified name, default value, and usage string.
 	// The return value is the address of a time.Duration variable that stores the value of the setting.
 	// The setting accepts a value acceptable to time.ParseDuration.
-	Duration(name string, value time.Duration, usage string) *time.Duration
+	Duration(name string, value time.Duration, usage string, opts ...Option) *time.Duration
+
+	// StringSliceVar defines a []string setting with specified name, default value, and usage string.
+	// The argument p points to a []string variable in which to store the value of the setting.
+	// The setting accepts a comma-separated list of strings.
+	//
+	// Returns itself so calls can be chained.
+	StringSliceVar(p *[]string, name string, value []string, usage string, opts ...Option) Congo
+	// StringSlice defines a []string setting with specified name, default value, and usage string.
+	// The return value is the address of a []string variable that stores the value of the setting.
+	// The setting accepts a comma-separated list of strings.
+	StringSlice(name string, value []string, usage string, opts ...Option) *[]string
+
+	// IntSliceVar defines a []int setting with specified name, default value, and usage string.
+	// The argument p points to a []int variable in which to store the value of the setting.
+	// The setting accepts a comma-separated list of integers.
+	//
+	// Returns itself so calls can be chained.
+	IntSliceVar(p *[]int, name string, value []int, usage string, opts ...Option) Congo
+	// IntSlice defines a []int setting with specified name, default value, and usage string.
+	// The return value is the address of a []int variable that stores the value of the setting.
+	// The setting accepts a comma-separated list of integers.
+	IntSlice(name string, value []int, usage string, opts ...Option) *[]int
+
+	// DurationSliceVar defines a []time.Duration setting with specified name, default value, and
+	// usage string.
+	// The argument p points to a []time.Duration variable in which to store the value of the setting.
+	// The setting accepts a comma-separated list of values acceptable to time.ParseDuration.
+	//
+	// Returns itself so calls can be chained.
+	DurationSliceVar(p *[]time.Duration, name string, value []time.Duration, usage string, opts ...Option) Congo
+	// DurationSlice defines a []time.Duration setting with specified name, default value, and
+	// usage string.
+	// The return value is the address of a []time.Duration variable that stores the value of the
+	// setting.
+	// The setting accepts a comma-separated list of values acceptable to time.ParseDuration.
+	DurationSlice(name string, value []time.Duration, usage string, opts ...Option) *[]time.Duration
+
+	// StringMapVar defines a map[string]string setting with specified name, default value, and
+	// usage string.
+	// The argument p points to a map[string]string variable in which to store the value of the
+	// setting.
+	// The setting accepts a comma-separated list of key=value pairs.
+	//
+	// Returns itself so calls can be chained.
+	StringMapVar(p *map[string]string, name string, value map[string]string, usage string, opts ...Option) Congo
+	// StringMap defines a map[string]string setting with specified name, default value, and
+	// usage string.
+	// The return value is the address of a map[string]string variable that stores the value of
+	// the setting.
+	// The setting accepts a comma-separated list of key=value pairs.
+	StringMap(name string, value map[string]string, usage string, opts ...Option) *map[string]string
+
+	// TextVar defines a setting with a specified name, default value, and usage string.
+	// The argument p must be a pointer to a variable that will hold the value
+	// of the setting, and p must implement encoding.TextUnmarshaler.
+	// If the setting is used, the setting value will be passed to p's UnmarshalText method.
+	// The type of the default value must be the same as the type of p.
+	//
+	// Returns itself so calls can be chained.
+	TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextMarshaler, usage string, opts ...Option) Congo
 
 	// Var defines a setting with the specified name and usage string. The type and
 	// value of the setting are represented by the first argument, of type Value, which
@@ -137,14 +294,77 @@ type Congo interface {
 	// decompose the comma-separated string into the slice.
 	//
 	// Returns itself so calls can be chained.
-	Var(value Value, name string, usage string) Congo
+	Var(value Value, name string, usage string, opts ...Option) Congo
+
+	// Name returns the name of the configuration.
+	Name() string
+
+	// PrintUsage writes the usage of all settings to given writer.
+	// Every setting is listed with its type, default value and help followed
+	// by the names sources implementing Namer know it by.
+	PrintUsage(w io.Writer)
 
 	// Init initializes the configuration sources.
+	// Sources implementing Attacher are attached to the configuration first.
 	Init() error
 
 	// Load loads the configuration from the sources.
+	// All sources are loaded even if some of them fail. The errors of all
+	// sources are returned as Errors. Required settings that weren't set by
+	// any source are reported with ErrRequired as cause. Afterwards the values
+	// are validated against their rules. Violations are reported as *LoadError
+	// with ErrInvalid as cause. Sources created by Lazy() are built first.
 	Load() error
 
+	// Origin returns where the value of the setting with given name came from.
+	// Settings that weren't set by any source during the last Load() originate from
+	// the DefaultSource.
+	// The settings of the entries of groups are found by their full names
+	// e.g. "upstream.a.host".
+	// Returns false if no setting with given name exists.
+	Origin(name string) (Origin, bool)
+
+	// IsSet returns whether any source set the setting with given name during the
+	// last Load() instead of the default value being used. Like Origin() it finds the
+	// settings of the entries of groups.
+	IsSet(name string) bool
+
+	// Reload loads the configuration from the sources again. Settings no source
+	// sets anymore fall back to their default value.
+	// If an error occurs all settings keep their previous values and the error is
+	// returned. Otherwise the listeners registered with OnChange() are notified
+	// about the settings that changed.
+	Reload() error
+
+	// OnChange registers a listener that is called with the settings that
+	// changed whenever Reload() changes any setting.
+	//
+	// Returns itself so calls can be chained.
+	OnChange(listener func([]Change)) Congo
+
+	// Read calls fn while no source changes the values of the settings.
+	// Variables and structs linked to settings must only be accessed within
+	// Read (or using Snapshot) while the configuration is reloaded concurrently.
+	//
+	// Other methods of the configuration must not be called within fn.
+	Read(fn func())
+
+	// Snapshot copies a struct given to Using() into the struct dst points to.
+	// The copy is consistent: it contains the values of a single load and
+	// isn't affected by later loads.
+	// Values of custom types that are referenced by pointers or interfaces
+	// are shared between the copy and the original.
+	//
+	// Snapshot panics if dst isn't a pointer to the type of a struct given to Using().
+	Snapshot(dst interface{})
+
+	// Watch reloads the configuration whenever one of the triggers fires until
+	// the context is done. Errors of reloads are written to the output of the
+	// configuration (os.Stderr).
+	//
+	// Returns the error of the context.
+	Watch(ctx context.Context, triggers ...Trigger) error
+
 	// Using takes an arbitrary struct and turns it into a configuration.
 	// Fields of the struct are read and linked to the configuration.
 	// Values of the fields are updated as soon as Load() is called.
@@ -156,10 +376,34 @@ type Congo interface {
 	//
 	// `usage`: Will be used as usage message (can be omitted).
 	//
+	// `sep`: Will be used to separate the elements of slices and maps (default: ",").
+	//
+	// `required`: If "true" the setting must be set by a source (see Required()).
+	//
+	// `secret`: If "true" the value of the setting is secret (see Secret()).
+	//
+	// `min`, `max`, `oneof`, `pattern`, `nonzero`, `minlen` and `maxlen`: Rules the value must
+	// follow (see Min(), Max(), OneOf(), Pattern(), NonZero(), MinLen() and MaxLen()).
+	//
 	// Supported types for field are: int, int64, uint, uint64, strings, float64, time.Duration
-	// and Value.
+	// []string, []int, []time.Duration, map[string]string and Value.
 	// A field that implements the Value type can be used to add custom, yet unsupported types.
-	// These fields will be directly added using the Var() method.
+	// These fields will be directly added using the Var() method. The same goes for fields
+	// whose pointer implements Value (e.g. a flag.Value).
+	// Fields implementing encoding.TextUnmarshaler (e.g. net.IP, time.Time or *big.Int) are
+	// set using UnmarshalText and formatted using MarshalText if available.
+	//
+	// Fields that are structs themselves are turned into settings recursively. The names of
+	// their settings are prefixed with the name of the field separated by NameSeparator
+	// (e.g. "database.pool.max-size"). Embedded structs without a name tag don't add a prefix.
+	//
+	// Fields of type []T or map[string]T where T is a struct hold a variable number of
+	// entries, like repeated sections of an ini file (see GroupValue). Every entry has the
+	// settings of the fields of T named after the field and the key of the entry
+	// (e.g. "upstream.a.host") including their defaults and rules. The field is replaced
+	// by the entries the sources provide on every load. A required group must have at least
+	// one entry, the entries of a secret group are secret and `nonzero`, `minlen` and `maxlen`
+	// apply to the number of entries. Other rules panic on groups.
 	//
 	// All other types will be ignored!
 	//
@@ -168,26 +412,29 @@ type Congo interface {
 }
 
 type congo struct {
-	sources  []Source            // sources for the settings
-	settings map[string]*Setting // settings
-	name     string              // name of the configuration
-	output   io.Writer
+	sources   []Source            // sources for the settings
+	settings  map[string]*Setting // settings
+	name      string              // name of the configuration
+	output    io.Writer
+	listeners []func([]Change) // notified about changes on reload
+	structs   []reflect.Value  // pointers to the structs given to Using
+	mu        sync.RWMutex     // guards the values of the settings
 }
 
 // BoolVar defines a bool setting with specified name, default value, and usage string.
 // The argument p points to a bool variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) BoolVar(p *bool, name string, value bool, usage string) Congo {
-	c.Var(newBoolValue(value, p), name, usage)
+func (c *congo) BoolVar(p *bool, name string, value bool, usage string, opts ...Option) Congo {
+	c.Var(newBoolValue(value, p), name, usage, opts...)
 	return c
 }
 
 // Bool defines a bool setting with specified name, default value, and usage string.
 // The return value is the address of a bool variable that stores the value of the setting.
-func (c *congo) Bool(name string, value bool, usage string) *bool {
+func (c *congo) Bool(name string, value bool, usage string, opts ...Option) *bool {
 	p := new(bool)
-	c.BoolVar(p, name, value, usage)
+	c.BoolVar(p, name, value, usage, opts...)
 	return p
 }
 
@@ -195,16 +442,16 @@ func (c *congo) Bool(name string, value bool, usage string) *bool {
 // The argument p points to an int variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) IntVar(p *int, name string, value int, usage string) Congo {
-	c.Var(newIntValue(value, p), name, usage)
+func (c *congo) IntVar(p *int, name string, value int, usage string, opts ...Option) Congo {
+	c.Var(newIntValue(value, p), name, usage, opts...)
 	return c
 }
 
 // Int defines an int setting with specified name, default value, and usage string.
 // The return value is the address of an int variable that stores the value of the setting.
-func (c *congo) Int(name string, value int, usage string) *int {
+func (c *congo) Int(name string, value int, usage string, opts ...Option) *int {
 	p := new(int)
-	c.IntVar(p, name, value, usage)
+	c.IntVar(p, name, value, usage, opts...)
 	return p
 }
 
@@ -212,16 +459,16 @@ func (c *congo) Int(name string, value int, usage string) *int {
 // The argument p points to an int64 variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) Int64Var(p *int64, name string, value int64, usage string) Congo {
-	c.Var(newInt64Value(value, p), name, usage)
+func (c *congo) Int64Var(p *int64, name string, value int64, usage string, opts ...Option) Congo {
+	c.Var(newInt64Value(value, p), name, usage, opts...)
 	return c
 }
 
 // Int64 defines an int64 setting with specified name, default value, and usage string.
 // The return value is the address of an int64 variable that stores the value of the setting.
-func (c *congo) Int64(name string, value int64, usage string) *int64 {
+func (c *congo) Int64(name string, value int64, usage string, opts ...Option) *int64 {
 	p := new(int64)
-	c.Int64Var(p, name, value, usage)
+	c.Int64Var(p, name, value, usage, opts...)
 	return p
 }
 
@@ -229,16 +476,16 @@ func (c *congo) Int64(name string, value int64, usage string) *int64 {
 // The argument p points to a uint variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) UintVar(p *uint, name string, value uint, usage string) Congo {
-	c.Var(newUintValue(value, p), name, usage)
+func (c *congo) UintVar(p *uint, name string, value uint, usage string, opts ...Option) Congo {
+	c.Var(newUintValue(value, p), name, usage, opts...)
 	return c
 }
 
 // Uint defines a uint setting with specified name, default value, and usage string.
 // The return value is the address of a uint variable that stores the value of the setting.
-func (c *congo) Uint(name string, value uint, usage string) *uint {
+func (c *congo) Uint(name string, value uint, usage string, opts ...Option) *uint {
 	p := new(uint)
-	c.UintVar(p, name, value, usage)
+	c.UintVar(p, name, value, usage, opts...)
 	return p
 }
 
@@ -246,16 +493,16 @@ func (c *congo) Uint(name string, value uint, usage string) *uint {
 // The argument p points to a uint64 variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) Uint64Var(p *uint64, name string, value uint64, usage string) Congo {
-	c.Var(newUint64Value(value, p), name, usage)
+func (c *congo) Uint64Var(p *uint64, name string, value uint64, usage string, opts ...Option) Congo {
+	c.Var(newUint64Value(value, p), name, usage, opts...)
 	return c
 }
 
 // Uint64 defines a uint64 setting with specified name, default value, and usage string.
 // The return value is the address of a uint64 variable that stores the value of the setting.
-func (c *congo) Uint64(name string, value uint64, usage string) *uint64 {
+func (c *congo) Uint64(name string, value uint64, usage string, opts ...Option) *uint64 {
 	p := new(uint64)
-	c.Uint64Var(p, name, value, usage)
+	c.Uint64Var(p, name, value, usage, opts...)
 	return p
 }
 
@@ -263,16 +510,16 @@ func (c *congo) Uint64(name string, value uint64, usage string) *uint64 {
 // The argument p points to a string variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) StringVar(p *string, name string, value string, usage string) Congo {
-	c.Var(newStringValue(value, p), name, usage)
+func (c *congo) StringVar(p *string, name string, value string, usage string, opts ...Option) Congo {
+	c.Var(newStringValue(value, p), name, usage, opts...)
 	return c
 }
 
 // String defines a string setting with specified name, default value, and usage string.
 // The return value is the address of a string variable that stores the value of the setting.
-func (c *congo) String(name string, value string, usage string) *string {
+func (c *congo) String(name string, value string, usage string, opts ...Option) *string {
 	p := new(string)
-	c.StringVar(p, name, value, usage)
+	c.StringVar(p, name, value, usage, opts...)
 	return p
 }
 
@@ -280,16 +527,16 @@ func (c *congo) String(name string, value string, usage string) *string {
 // The argument p points to a float64 variable in which to store the value of the setting.
 //
 // Returns itself so calls can be chained.
-func (c *congo) Float64Var(p *float64, name string, value float64, usage string) Congo {
-	c.Var(newFloat64Value(value, p), name, usage)
+func (c *congo) Float64Var(p *float64, name string, value float64, usage string, opts ...Option) Congo {
+	c.Var(newFloat64Value(value, p), name, usage, opts...)
 	return c
 }
 
 // Float64 defines a float64 setting with specified name, default value, and usage string.
 // The return value is the address of a float64 variable that stores the value of the setting.
-func (c *congo) Float64(name string, value float64, usage string) *float64 {
+func (c *congo) Float64(name string, value float64, usage string, opts ...Option) *float64 {
 	p := new(float64)
-	c.Float64Var(p, name, value, usage)
+	c.Float64Var(p, name, value, usage, opts...)
 	return p
 }
 
@@ -298,20 +545,118 @@ func (c *congo) Float64(name string, value float64, usage string) *float64 {
 // The setting accepts a value acceptable to time.ParseDuration.
 //
 // Returns itself so calls can be chained.
-func (c *congo) DurationVar(p *time.Duration, name string, value time.Duration, usage string) Congo {
-	c.Var(newDurationValue(value, p), name, usage)
+func (c *congo) DurationVar(p *time.Duration, name string, value time.Duration, usage string, opts ...Option) Congo {
+	c.Var(newDurationValue(value, p), name, usage, opts...)
 	return c
 }
 
 // Duration defines a time.Duration setting with specified name, default value, and usage string.
 // The return value is the address of a time.Duration variable that stores the value of the setting.
 // The setting accepts a value acceptable to time.ParseDuration.
-func (c *congo) Duration(name string, value time.Duration, usage string) *time.Duration {
+func (c *congo) Duration(name string, value time.Duration, usage string, opts ...Option) *time.Duration {
 	p := new(time.Duration)
-	c.DurationVar(p, name, value, usage)
+	c.DurationVar(p, name, value, usage, opts...)
+	return p
+}
+
+// StringSliceVar defines a []string setting with specified name, default value, and usage string.
+// The argument p points to a []string variable in which to store the value of the setting.
+// The setting accepts a comma-separated list of strings.
+//
+// Returns itself so calls can be chained.
+func (c *congo) StringSliceVar(p *[]string, name string, value []string, usage string, opts ...Option) Congo {
+	c.Var(newStringSliceValue(value, p, defaultSeparator), name, usage, opts...)
+	return c
+}
+
+// StringSlice defines a []string setting with specified name, default value, and usage string.
+// The return value is the address of a []string variable that stores the value of the setting.
+// The setting accepts a comma-separated list of strings.
+func (c *congo) StringSlice(name string, value []string, usage string, opts ...Option) *[]string {
+	p := new([]string)
+	c.StringSliceVar(p, name, value, usage, opts...)
+	return p
+}
+
+// IntSliceVar defines a []int setting with specified name, default value, and usage string.
+// The argument p points to a []int variable in which to store the value of the setting.
+// The setting accepts a comma-separated list of integers.
+//
+// Returns itself so calls can be chained.
+func (c *congo) IntSliceVar(p *[]int, name string, value []int, usage string, opts ...Option) Congo {
+	c.Var(newIntSliceValue(value, p, defaultSeparator), name, usage, opts...)
+	return c
+}
+
+// IntSlice defines a []int setting with specified name, default value, and usage string.
+// The return value is the address of a []int variable that stores the value of the setting.
+// The setting accepts a comma-separated list of integers.
+func (c *congo) IntSlice(name string, value []int, usage string, opts ...Option) *[]int {
+	p := new([]int)
+	c.IntSliceVar(p, name, value, usage, opts...)
+	return p
+}
+
+// DurationSliceVar defines a []time.Duration setting with specified name, default value, and
+// usage string.
+// The argument p points to a []time.Duration variable in which to store the value of the setting.
+// The setting accepts a comma-separated list of values acceptable to time.ParseDuration.
+//
+// Returns itself so calls can be chained.
+func (c *congo) DurationSliceVar(p *[]time.Duration, name string, value []time.Duration,
+	usage string, opts ...Option) Congo {
+	c.Var(newDurationSliceValue(value, p, defaultSeparator), name, usage, opts...)
+	return c
+}
+
+// DurationSlice defines a []time.Duration setting with specified name, default value, and
+// usage string.
+// The return value is the address of a []time.Duration variable that stores the value of the
+// setting.
+// The setting accepts a comma-separated list of values acceptable to time.ParseDuration.
+func (c *congo) DurationSlice(name string, value []time.Duration, usage string, opts ...Option) *[]time.Duration {
+	p := new([]time.Duration)
+	c.DurationSliceVar(p, name, value, usage, opts...)
 	return p
 }
 
+// StringMapVar defines a map[string]string setting with specified name, default value, and
+// usage string.
+// The argument p points to a map[string]string variable in which to store the value of the
+// setting.
+// The setting accepts a comma-separated list of key=value pairs.
+//
+// Returns itself so calls can be chained.
+func (c *congo) StringMapVar(p *map[string]string, name string, value map[string]string,
+	usage string, opts ...Option) Congo {
+	c.Var(newStringMapValue(value, p, defaultSeparator), name, usage, opts...)
+	return c
+}
+
+// StringMap defines a map[string]string setting with specified name, default value, and
+// usage string.
+// The return value is the address of a map[string]string variable that stores the value of
+// the setting.
+// The setting accepts a comma-separated list of key=value pairs.
+func (c *congo) StringMap(name string, value map[string]string, usage string, opts ...Option) *map[string]string {
+	p := new(map[string]string)
+	c.StringMapVar(p, name, value, usage, opts...)
+	return p
+}
+
+// TextVar defines a setting with a specified name, default value, and usage string.
+// The argument p must be a pointer to a variable that will hold the value
+// of the setting, and p must implement encoding.TextUnmarshaler.
+// If the setting is used, the setting value will be passed to p's UnmarshalText method.
+// The type of the default value must be the same as the type of p.
+//
+// Returns itself so calls can be chained.
+func (c *congo) TextVar(p encoding.TextUnmarshaler, name string, value encoding.TextMarshaler,
+	usage string, opts ...Option) Congo {
+	c.Var(newTextValue(value, p), name, usage, opts...)
+	return c
+}
+
 // Var defines a setting with the specified name and usage string. The type and
 // value of the setting are represented by the first argument, of type Value, which
 // typically holds a user-defined implementation of Value. For instance, the
@@ -320,9 +665,12 @@ func (c *congo) Duration(name string, value time.Duration, usage string) *time.D
 // decompose the comma-separated string into the slice.
 //
 // Returns itself so calls can be chained.
-func (c *congo) Var(value Value, name string, usage string) Congo {
+func (c *congo) Var(value Value, name string, usage string, opts ...Option) Congo {
 	// Remember the default value as a string; it won't change.
-	setting := &Setting{name, usage, value, value.String()}
+	setting := &Setting{Name: name, Usage: usage, Value: value, DefValue: value.String()}
+	for _, opt := range opts {
+		opt(setting)
+	}
 	_, alreadythere := c.settings[name]
 	if alreadythere {
 		var msg string
@@ -342,7 +690,13 @@ func (c *congo) Var(value Value, name string, usage string) Congo {
 }
 
 // Init initializes the configuration sources.
+// Sources implementing Attacher are attached to the configuration first.
 func (c *congo) Init() error {
+	for _, source := range c.sources {
+		if attacher, ok := source.(Attacher); ok {
+			attacher.Attach(c)
+		}
+	}
 	for i := len(c.sources) - 1; i >= 0; i-- {
 		if err := c.sources[i].Init(c.settings); err != nil {
 			return err
@@ -352,13 +706,65 @@ func (c *congo) Init() error {
 }
 
 // Load loads the configuration from the sources.
+// All sources are loaded even if some of them fail. The errors of all
+// sources are returned as Errors. Required settings that weren't set by
+// any source are reported with ErrRequired as cause. Afterwards the values
+// are validated against their rules. Violations are reported as *LoadError
+// with ErrInvalid as cause. Sources created by Lazy() are built first.
 func (c *congo) Load() error {
-	for i := len(c.sources) - 1; i >= 0; i-- {
-		if err := c.sources[i].Load(c.settings); err != nil {
-			return err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load()
+}
+
+// load loads the configuration from the sources. The caller must hold the lock.
+func (c *congo) load() error {
+	for _, setting := range c.settings {
+		setting.Origin = nil
+		if group, ok := setting.Value.(*groupValue); ok {
+			// Entries are created by the sources again.
+			group.reset()
 		}
 	}
-	return nil
+	var errs Errors
+	if !c.bootstrap(&errs) {
+		for i := len(c.sources) - 1; i >= 0; i-- {
+			errs.Append(c.sources[i].Load(c.settings))
+		}
+	}
+	for _, name := range Names(c.settings) {
+		errs.Append(c.settings[name].check())
+	}
+	return errs.Err()
+}
+
+// Origin returns where the value of the setting with given name came from.
+// Settings that weren't set by any source during the last Load() originate from
+// the DefaultSource.
+// The settings of the entries of groups are found by their full names
+// e.g. "upstream.a.host".
+// Returns false if no setting with given name exists.
+func (c *congo) Origin(name string) (Origin, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	setting, ok := lookup(c.settings, name)
+	if !ok {
+		return Origin{}, false
+	}
+	if setting.Origin == nil {
+		return Origin{Source: DefaultSource, Raw: setting.DefValue}, true
+	}
+	return *setting.Origin, true
+}
+
+// IsSet returns whether any source set the setting with given name during the
+// last Load() instead of the default value being used. Like Origin() it finds the
+// settings of the entries of groups.
+func (c *congo) IsSet(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	setting, ok := lookup(c.settings, name)
+	return ok && setting.Origin != nil
 }
 
 // Using takes an arbitrary struct and turns it into settings.
@@ -374,10 +780,34 @@ func (c *congo) Load() error {
 //
 // `usage`: Will be used as usage message (can be omitted).
 //
+// `sep`: Will be used to separate the elements of slices and maps (default: ",").
+//
+// `required`: If "true" the setting must be set by a source (see Required()).
+//
+// `secret`: If "true" the value of the setting is secret (see Secret()).
+//
+// `min`, `max`, `oneof`, `pattern`, `nonzero`, `minlen` and `maxlen`: Rules the value must
+// follow (see Min(), Max(), OneOf(), Pattern(), NonZero(), MinLen() and MaxLen()).
+//
 // Supported types for field are: int, int64, uint, uint64, strings, float64, time.Duration
-// and Value.
+// []string, []int, []time.Duration, map[string]string and Value.
 // A field that implements the Value type can be used to add custom, yet unsupported types.
-// These fields will be directly added using the Var() method.
+// These fields will be directly added using the Var() method. The same goes for fields
+// whose pointer implements Value (e.g. a flag.Value).
+// Fields implementing encoding.TextUnmarshaler (e.g. net.IP, time.Time or *big.Int) are
+// set using UnmarshalText and formatted using MarshalText if available.
+//
+// Fields that are structs themselves are turned into settings recursively. The names of
+// their settings are prefixed with the name of the field separated by NameSeparator
+// (e.g. "database.pool.max-size"). Embedded structs without a name tag don't add a prefix.
+//
+// Fields of type []T or map[string]T where T is a struct hold a variable number of
+// entries, like repeated sections of an ini file (see GroupValue). Every entry has the
+// settings of the fields of T named after the field and the key of the entry
+// (e.g. "upstream.a.host") including their defaults and rules. The field is replaced
+// by the entries the sources provide on every load. A required group must have at least
+// one entry, the entries of a secret group are secret and `nonzero`, `minlen` and `maxlen`
+// apply to the number of entries. Other rules panic on groups.
 //
 // All other types, unexported fields or nil-pointers will be ignored!
 //
@@ -390,52 +820,148 @@ func (c *congo) Using(configurationStructPtr interface{}) Congo {
 		panic("Using only supports pointers to structs. If configurationStructPtr " +
 			"isn't a pointer the fields of the struct can't be linked to their settings.")
 	}
-	e := v.Elem()
-	for i := 0; i < e.NumField(); i++ {
-		c.register(e.Type().Field(i), e.Field(i))
-	}
+	c.registerStruct("", v.Elem())
+	c.structs = append(c.structs, v)
 	return c
 }
 
+// NameSeparator separates the parts of a hierarchical setting name.
+// Settings of nested structs are named after the path of fields leading
+// to them e.g. "database.pool.max-size".
+const NameSeparator = "."
+
 const (
-	usageTag = "usage"
-	nameTag  = "name"
+	usageTag    = "usage"
+	nameTag     = "name"
+	sepTag      = "sep"
+	requiredTag = "required"
+	secretTag   = "secret"
 )
 
+// defaultSeparator separates the elements of slice and map settings
+// if no other separator is given.
+const defaultSeparator = ","
+
+// registerStruct registers all fields of given struct value into the settings.
+// The names of the settings are prefixed with given prefix.
+func (c *congo) registerStruct(prefix string, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		c.register(prefix, v.Type().Field(i), v.Field(i))
+	}
+}
+
 // register registers a StructField with given value into the settings
 // the type of the value is converted into a Value and added as settings
 // using additional information from tags.
-func (c *congo) register(f reflect.StructField, v reflect.Value) {
+func (c *congo) register(prefix string, f reflect.StructField, v reflect.Value) {
+	name, ok := f.Tag.Lookup(nameTag)
+	if !ok {
+		name = f.Name
+	}
+	if prefix != "" {
+		name = prefix + NameSeparator + name
+	}
+	// Embedded structs are flattened into the parent unless they are named
+	// explicitly. Their exported fields can be set even if the embedded type
+	// itself is unexported.
+	if f.Anonymous && v.Kind() == reflect.Struct && v.CanAddr() {
+		if !ok {
+			name = prefix
+		}
+		c.registerStruct(name, v)
+		return
+	}
 	// Ignore unaddressable and unexported values
 	if !v.CanAddr() || !v.CanSet() {
 		return
 	}
 	usage := f.Tag.Get(usageTag)
-	name, ok := f.Tag.Lookup(nameTag)
+	sep, ok := f.Tag.Lookup(sepTag)
 	if !ok {
-		name = f.Name
+		sep = defaultSeparator
 	}
+	opts := tagOptions(f)
 	p := v.Addr().Interface()
 	switch a := v.Interface().(type) {
 	case bool:
-		c.BoolVar(p.(*bool), name, a, usage)
+		c.BoolVar(p.(*bool), name, a, usage, opts...)
 	case int:
-		c.IntVar(p.(*int), name, a, usage)
+		c.IntVar(p.(*int), name, a, usage, opts...)
 	case int64:
-		c.Int64Var(p.(*int64), name, a, usage)
+		c.Int64Var(p.(*int64), name, a, usage, opts...)
 	case uint:
-		c.UintVar(p.(*uint), name, a, usage)
+		c.UintVar(p.(*uint), name, a, usage, opts...)
 	case uint64:
-		c.Uint64Var(p.(*uint64), name, a, usage)
+		c.Uint64Var(p.(*uint64), name, a, usage, opts...)
 	case string:
-		c.StringVar(p.(*string), name, a, usage)
+		c.StringVar(p.(*string), name, a, usage, opts...)
 	case float64:
-		c.Float64Var(p.(*float64), name, a, usage)
+		c.Float64Var(p.(*float64), name, a, usage, opts...)
 	case time.Duration:
-		c.DurationVar(p.(*time.Duration), name, a, usage)
+		c.DurationVar(p.(*time.Duration), name, a, usage, opts...)
+	case []string:
+		c.Var(newStringSliceValue(a, p.(*[]string), sep), name, usage, opts...)
+	case []int:
+		c.Var(newIntSliceValue(a, p.(*[]int), sep), name, usage, opts...)
+	case []time.Duration:
+		c.Var(newDurationSliceValue(a, p.(*[]time.Duration), sep), name, usage, opts...)
+	case map[string]string:
+		c.Var(newStringMapValue(a, p.(*map[string]string), sep), name, usage, opts...)
+	case Value:
+		c.Var(a, name, usage, opts...)
+	case encoding.TextUnmarshaler:
+		// Non-nil pointers to types implementing encoding.TextUnmarshaler
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			c.Var(newTextPointerValue(v), name, usage, opts...)
+		}
+	default:
+		c.registerAddressable(name, usage, v, p, opts)
+	}
+}
+
+// tagOptions returns the options described by the tags of given field.
+// It panics if a tag can't be parsed.
+func tagOptions(f reflect.StructField) []Option {
+	var opts []Option
+	if boolTag(f, requiredTag) {
+		opts = append(opts, Required())
+	}
+	if boolTag(f, secretTag) {
+		opts = append(opts, Secret())
+	}
+	return append(opts, ruleOptions(f)...)
+}
+
+// boolTag returns whether the tag of given field is "true". Missing tags are false.
+// It panics if the tag isn't a bool, so typos like "ture" don't go unnoticed.
+func boolTag(f reflect.StructField, tag string) bool {
+	value, ok := f.Tag.Lookup(tag)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s tag of field %s: %s", tag, f.Name, err))
+	}
+	return b
+}
+
+// registerAddressable registers a field that is only supported through its
+// address e.g. because Value is implemented on the pointer receiver.
+// Structs not supported this way are registered recursively.
+func (c *congo) registerAddressable(name string, usage string, v reflect.Value, p interface{},
+	opts []Option) {
+	switch a := p.(type) {
 	case Value:
-		c.Var(a, name, usage)
+		c.Var(a, name, usage, opts...)
+	case encoding.TextUnmarshaler:
+		c.Var(newTextValue(nil, a), name, usage, opts...)
 	default:
-		// Do nothing.
+		if v.Kind() == reflect.Struct {
+			c.registerStruct(name, v)
+		} else if isGroup(v.Type()) {
+			c.registerGroup(name, usage, v, opts)
+		}
+		// Ignore everything else.
 	}
 }
@@ -2,6 +2,11 @@ package congo
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -19,6 +24,7 @@ type testSource struct {
 	LoadParam map[string]*Setting
 	InitErr   error
 	LoadErr   error
+	LoadFunc  func(map[string]*Setting) error // called on load if set
 }
 
 func (t *testSource) Init(param map[string]*Setting) error {
@@ -28,6 +34,9 @@ func (t *testSource) Init(param map[string]*Setting) error {
 
 func (t *testSource) Load(param map[string]*Setting) error {
 	t.LoadParam = param
+	if t.LoadFunc != nil {
+		return t.LoadFunc(param)
+	}
 	return t.LoadErr
 }
 
@@ -63,10 +72,10 @@ func setupTestCongo() (Congo, *testSource) {
 	sources := []Source{s}
 	output := bytes.NewBufferString("")
 	c := congo{
-		sources,
-		make(map[string]*Setting),
-		"test",
-		output,
+		sources:  sources,
+		settings: make(map[string]*Setting),
+		name:     "test",
+		output:   output,
 	}
 	return &c, s
 }
@@ -245,3 +254,363 @@ func testForPanic(t *testing.T) {
 		t.Errorf("Expected code to panic but it didn't.")
 	}
 }
+
+type testPool struct {
+	MaxSize int `name:"max-size"`
+}
+
+type testEmbedded struct {
+	Verbose bool `name:"verbose"`
+}
+
+type testNamedEmbedded struct {
+	Level int `name:"level"`
+}
+
+type testNestedStruct struct {
+	Database struct {
+		Host string   `name:"host"`
+		Pool testPool `name:"pool"`
+	} `name:"database"`
+	Cache testPool
+	testEmbedded
+	testNamedEmbedded `name:"log"`
+}
+
+func TestCongo_Using_Nested(t *testing.T) {
+	settings := testNestedStruct{}
+	settings.Database.Pool.MaxSize = 5
+	expected := map[string]string{
+		"database.host":          "",
+		"database.pool.max-size": "5",
+		"Cache.max-size":         "0",
+		"verbose":                "false",
+		"log.level":              "0",
+	}
+	c, s := setupTestCongo()
+	c.Using(&settings)
+	c.Init()
+	params := s.InitParam
+	if len(params) != len(expected) {
+		t.Errorf("To much/less settings interpreted. Expected: %d\n"+
+			"But got: %d\n", len(expected), len(params))
+	}
+	for name, defValue := range expected {
+		s, ok := params[name]
+		if !ok {
+			t.Errorf("Expected %q to be in the settings.\nBut was not.\n", name)
+			continue
+		}
+		if s.DefValue != defValue {
+			t.Errorf("Expected default value for %q to be: %s\n"+
+				"But got: %s\n", name, defValue, s.DefValue)
+		}
+	}
+	params["database.pool.max-size"].Value.Set("12")
+	if settings.Database.Pool.MaxSize != 12 {
+		t.Errorf("Expected nested field to be set to %d.\nBut was set to %d.\n",
+			12, settings.Database.Pool.MaxSize)
+	}
+}
+
+func TestCongo_StringSlice(t *testing.T) {
+	c, s := setupTestCongo()
+	v := c.StringSlice("test", []string{"a"}, "Usage")
+	c.Load()
+	param := s.LoadParam["test"]
+	if param.DefValue != "a" {
+		t.Errorf("Expected default value to be %q.\nBut was %q.\n", "a", param.DefValue)
+	}
+	param.Value.Set("b, c")
+	param.Value.(SliceValue).Append("d")
+	if strings.Join(*v, "|") != "b|c|d" {
+		t.Errorf("Expected value to be set to %v.\nBut was set to %v.\n",
+			[]string{"b", "c", "d"}, *v)
+	}
+}
+
+func TestCongo_IntSlice(t *testing.T) {
+	c, s := setupTestCongo()
+	v := c.IntSlice("test", nil, "Usage")
+	c.Load()
+	param := s.LoadParam["test"]
+	param.Value.Set("1,0x10")
+	param.Value.(SliceValue).Append("3")
+	if len(*v) != 3 || (*v)[0] != 1 || (*v)[1] != 16 || (*v)[2] != 3 {
+		t.Errorf("Expected value to be set to %v.\nBut was set to %v.\n", []int{1, 16, 3}, *v)
+	}
+	if err := param.Value.Set("1,a"); err == nil {
+		t.Errorf("Expected invalid element to cause an error.\nBut no error was returned.\n")
+	}
+}
+
+func TestCongo_DurationSlice(t *testing.T) {
+	c, s := setupTestCongo()
+	v := c.DurationSlice("test", []time.Duration{time.Second}, "Usage")
+	c.Load()
+	param := s.LoadParam["test"]
+	param.Value.Set("1m,2h")
+	if len(*v) != 2 || (*v)[0] != time.Minute || (*v)[1] != 2*time.Hour {
+		t.Errorf("Expected value to be set to %v.\nBut was set to %v.\n",
+			[]time.Duration{time.Minute, 2 * time.Hour}, *v)
+	}
+	if param.Value.String() != "1m0s,2h0m0s" {
+		t.Errorf("Expected string to be %q.\nBut was %q.\n", "1m0s,2h0m0s", param.Value.String())
+	}
+}
+
+func TestCongo_StringMap(t *testing.T) {
+	c, s := setupTestCongo()
+	v := c.StringMap("test", map[string]string{"a": "1"}, "Usage")
+	c.Load()
+	param := s.LoadParam["test"]
+	param.Value.Set("b=2, c = 3")
+	param.Value.(SliceValue).Append("d=4")
+	if param.Value.String() != "b=2,c=3,d=4" {
+		t.Errorf("Expected value to be set to %q.\nBut was set to %q.\n",
+			"b=2,c=3,d=4", param.Value.String())
+	}
+	if (*v)["c"] != "3" {
+		t.Errorf("Expected value of %q to be %q.\nBut was %q.\n", "c", "3", (*v)["c"])
+	}
+	if err := param.Value.Set("invalid"); err == nil {
+		t.Errorf("Expected invalid entry to cause an error.\nBut no error was returned.\n")
+	}
+}
+
+func TestCongo_Using_Slices(t *testing.T) {
+	settings := struct {
+		Tags     []string `name:"tags" sep:";"`
+		Ports    []int    `name:"ports"`
+		Timeouts []time.Duration
+		Labels   map[string]string `name:"labels"`
+	}{Tags: []string{"a", "b"}}
+	c, s := setupTestCongo()
+	c.Using(&settings)
+	c.Init()
+	params := s.InitParam
+	if len(params) != 4 {
+		t.Errorf("To much/less settings interpreted. Expected: %d\n"+
+			"But got: %d\n", 4, len(params))
+	}
+	if params["tags"].DefValue != "a;b" {
+		t.Errorf("Expected default value to be %q.\nBut was %q.\n", "a;b", params["tags"].DefValue)
+	}
+	params["tags"].Value.Set("x,y;z")
+	if len(settings.Tags) != 2 || settings.Tags[0] != "x,y" {
+		t.Errorf("Expected value to be split at separator.\nBut was set to %v.\n", settings.Tags)
+	}
+}
+
+// testLevel is a custom type implementing encoding.TextUnmarshaler.
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func (l testLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"debug", "info"}[l]), nil
+}
+
+// testPointerValue implements Value on its pointer receiver.
+type testPointerValue struct {
+	value string
+}
+
+func (v *testPointerValue) String() string { return v.value }
+
+func (v *testPointerValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+func TestCongo_Using_Text(t *testing.T) {
+	settings := struct {
+		Level   testLevel        `name:"level"`
+		IP      net.IP           `name:"ip"`
+		Big     *big.Int         `name:"big"`
+		NilBig  *big.Int         `name:"nil-big"`
+		Start   time.Time        `name:"start"`
+		Pointer testPointerValue `name:"pointer"`
+	}{
+		Level:   1,
+		IP:      net.IPv4(127, 0, 0, 1),
+		Big:     big.NewInt(5),
+		Pointer: testPointerValue{"default"},
+	}
+	expected := map[string]string{
+		"level":   "info",
+		"ip":      "127.0.0.1",
+		"big":     "5",
+		"start":   "0001-01-01T00:00:00Z",
+		"pointer": "default",
+	}
+	c, s := setupTestCongo()
+	c.Using(&settings)
+	c.Init()
+	params := s.InitParam
+	if len(params) != len(expected) {
+		t.Errorf("To much/less settings interpreted. Expected: %d\n"+
+			"But got: %d\n", len(expected), len(params))
+	}
+	for name, defValue := range expected {
+		s, ok := params[name]
+		if !ok {
+			t.Errorf("Expected %q to be in the settings.\nBut was not.\n", name)
+			continue
+		}
+		if s.DefValue != defValue {
+			t.Errorf("Expected default value for %q to be: %s\n"+
+				"But got: %s\n", name, defValue, s.DefValue)
+		}
+	}
+	params["level"].Value.Set("debug")
+	params["ip"].Value.Set("10.0.0.1")
+	params["big"].Value.Set("123456789012345678901234567890")
+	params["pointer"].Value.Set("changed")
+	if settings.Level != 0 || settings.IP.String() != "10.0.0.1" ||
+		settings.Big.String() != "123456789012345678901234567890" ||
+		settings.Pointer.value != "changed" {
+		t.Errorf("Expected fields to be set.\nBut got: %+v\n", settings)
+	}
+	if err := params["level"].Value.Set("unknown"); err == nil {
+		t.Errorf("Expected invalid value to cause an error.\nBut no error was returned.\n")
+	}
+}
+
+func TestCongo_TextVar(t *testing.T) {
+	c, s := setupTestCongo()
+	var ip net.IP
+	c.TextVar(&ip, "test", net.IPv4(10, 0, 0, 1), "Usage")
+	c.Load()
+	param := s.LoadParam["test"]
+	if param.DefValue != "10.0.0.1" || ip.String() != "10.0.0.1" {
+		t.Errorf("Expected default value to be %q.\nBut was %q.\n", "10.0.0.1", param.DefValue)
+	}
+	param.Value.Set("::1")
+	if ip.String() != "::1" {
+		t.Errorf("Expected value to be set to %s.\nBut was set to %s.\n", "::1", ip)
+	}
+}
+
+func TestCongo_Origin(t *testing.T) {
+	c, s := setupTestCongo()
+	c.Int("set", 0, "Usage")
+	c.Int("unset", 5, "Usage")
+	c.Load()
+	s.LoadParam["set"].Set("3", Origin{Source: "test", Location: "somewhere"})
+
+	origin, ok := c.Origin("set")
+	expected := Origin{Source: "test", Raw: "3", Location: "somewhere"}
+	if !ok || origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+	origin, ok = c.Origin("unset")
+	expected = Origin{Source: DefaultSource, Raw: "5"}
+	if !ok || origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+	if _, ok := c.Origin("unknown"); ok {
+		t.Errorf("Expected unknown setting to have no origin.\n")
+	}
+
+	// Origins are reset on every load
+	c.Load()
+	if origin, _ := c.Origin("set"); origin.Source != DefaultSource {
+		t.Errorf("Expected origin to be reset on load.\nBut got: %+v\n", origin)
+	}
+}
+
+func TestCongo_Load_Errors(t *testing.T) {
+	cause := errors.New("cause")
+	first := &testSource{LoadErr: errors.New("first")}
+	second := &testSource{LoadErr: Errors{
+		&LoadError{"a", "test", "", "x", cause},
+		&LoadError{"b", "test", "", "y", errors.New("other")},
+	}}
+	c := New("test", first, second)
+	err := c.Load()
+	if first.LoadParam == nil || second.LoadParam == nil {
+		t.Errorf("Expected all sources to be loaded despite errors.\n")
+	}
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expected errors of all sources to be returned.\nBut got: %v\n", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("Expected errors.Is to find the cause of a load error.\n")
+	}
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Setting != "a" {
+		t.Errorf("Expected errors.As to find the first load error.\nBut got: %v\n", loadErr)
+	}
+}
+
+func TestSetting_Set_Error(t *testing.T) {
+	c, s := setupTestCongo()
+	c.Int("number", 0, "Usage")
+	c.Load()
+	err := s.LoadParam["number"].Set("abc", Origin{Source: "test", Location: "here"})
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("Expected a *LoadError.\nBut got: %v\n", err)
+	}
+	expected := LoadError{"number", "test", "here", "abc", loadErr.Err}
+	if *loadErr != expected {
+		t.Errorf("Expected error to be %+v.\nBut got: %+v\n", expected, *loadErr)
+	}
+	if s.LoadParam["number"].Origin != nil {
+		t.Errorf("Expected failed set to not record an origin.\n")
+	}
+}
+
+func TestCongo_Required(t *testing.T) {
+	settings := struct {
+		Host string `name:"host" required:"true"`
+		Port int    `name:"port" required:"false"`
+	}{}
+	c, s := setupTestCongo()
+	c.Using(&settings)
+	c.Int("user", 0, "Usage", Required())
+	c.Int("group", 0, "Usage", Required())
+	c.Init()
+	if !s.InitParam["host"].Required || s.InitParam["port"].Required {
+		t.Errorf("Expected only tagged fields to be required.\n")
+	}
+
+	err := c.Load()
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expected all missing settings to be reported.\nBut got: %v\n", err)
+	}
+	if !errors.Is(err, ErrRequired) {
+		t.Errorf("Expected missing settings to be caused by ErrRequired.\n")
+	}
+	var settingErr *SettingError
+	if !errors.As(errs[0], &settingErr) || settingErr.Setting != "group" {
+		t.Errorf("Expected first missing setting to be %q.\nBut got: %v\n", "group", errs[0])
+	}
+}
+
+func TestCongo_IsSet(t *testing.T) {
+	c, s := setupTestCongo()
+	c.Int("set", 0, "Usage")
+	c.Int("unset", 0, "Usage")
+	c.Load()
+	s.LoadParam["set"].Set("0", Origin{Source: "test"})
+	if !c.IsSet("set") {
+		t.Errorf("Expected setting set by a source to be set.\n")
+	}
+	if c.IsSet("unset") || c.IsSet("unknown") {
+		t.Errorf("Expected settings not set by a source to be unset.\n")
+	}
+}
@@ -0,0 +1,136 @@
+package congo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// ErrRequired is the cause of errors for required settings that
+// weren't set by any source.
+var ErrRequired = errors.New("required setting is not set")
+
+// ErrUnknown is the cause of errors for keys of a source that don't
+// belong to any setting.
+var ErrUnknown = errors.New("unknown setting")
+
+// UnknownError describes a key of a source that doesn't belong to any
+// setting e.g. a misspelled key in a file. Sources only report unknown
+// keys in strict mode.
+type UnknownError struct {
+	Source     string // name of the source that provided the key
+	Key        string // key as named by the source
+	Location   string // location of the key in the source (may be empty)
+	Suggestion string // known key most similar to Key (may be empty)
+}
+
+// Error returns a description of the error.
+func (e *UnknownError) Error() string {
+	msg := fmt.Sprintf("%s-source: unknown key %q", e.Source, e.Key)
+	if e.Location != "" {
+		msg += " at " + e.Location
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// Unwrap returns ErrUnknown.
+func (e *UnknownError) Unwrap() error {
+	return ErrUnknown
+}
+
+// SettingError describes a problem with a setting that isn't caused
+// by a specific value of a source.
+type SettingError struct {
+	Setting string // name of the setting
+	Err     error  // cause of the error
+}
+
+// Error returns a description of the error.
+func (e *SettingError) Error() string {
+	return fmt.Sprintf("setting %q: %s", e.Setting, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *SettingError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError describes a failure to set a setting from a value provided
+// by a source.
+type LoadError struct {
+	Setting  string // name of the setting
+	Source   string // name of the source that provided the value
+	Location string // location of the value in the source (may be empty)
+	Raw      string // value as given by the source
+	Err      error  // cause of the failure
+}
+
+// Error returns a description of the error.
+func (e *LoadError) Error() string {
+	msg := fmt.Sprintf("%s-source: couldn't read setting %q from value %q", e.Source, e.Setting, e.Raw)
+	if e.Location != "" {
+		msg += " at " + e.Location
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the error.
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of errors that occurred while loading a configuration.
+// Sources collect all errors instead of stopping at the first one, so all
+// problems of a configuration can be fixed at once.
+//
+// errors.Is and errors.As consider every error of the list.
+type Errors []error
+
+// Error returns a description of all errors.
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "\t" + err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// Unwrap returns the errors of the list.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Append adds err to the list. Nil errors are ignored and other
+// Errors are added element by element.
+func (e *Errors) Append(err error) {
+	switch err := err.(type) {
+	case nil:
+		return
+	case Errors:
+		*e = append(*e, err...)
+	default:
+		*e = append(*e, err)
+	}
+}
+
+// Err returns nil if the list is empty and the list itself otherwise.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -82,15 +82,16 @@ func (s *ExampleSource) Load(settings map[string]*Setting) error {
 	// These could also be loaded from a file or other possible sources.
 	//
 	// Most sources will iterate over the settings and resolve them using their key.
-	// After that they will set the value accordingly.
+	// After that they will set the value accordingly and record where it came from.
+	origin := Origin{Source: "example"}
 
-	if err := settings["update-interval"].Value.Set("1h"); err != nil {
+	if err := settings["update-interval"].Set("1h", origin); err != nil {
 		return err
 	}
-	if err := settings["MagicNumber"].Value.Set("0"); err != nil {
+	if err := settings["MagicNumber"].Set("0", origin); err != nil {
 		return err
 	}
-	if err := settings["Custom"].Value.Set("9:8"); err != nil {
+	if err := settings["Custom"].Set("9:8", origin); err != nil {
 		return err
 	}
 	return nil
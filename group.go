@@ -0,0 +1,237 @@
+package congo
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// GroupValue is the value of a setting holding a variable number of entries
+// that have settings of their own, like the elements of a []T or map[string]T
+// field of a struct given to Using() where T is a struct.
+//
+// Sources supporting groups create the entries by their keys (e.g. the
+// sections [upstream "a"] and [upstream "b"] of an ini file) and set the
+// settings of each entry like any other settings. Sources that don't support
+// groups can't set them: Set only accepts the empty default.
+type GroupValue interface {
+	Value
+	// Entry returns the settings of the entry with given key by their names
+	// relative to the entry. The entry is created if it doesn't exist yet.
+	Entry(key string) map[string]*Setting
+	// Keys returns the keys of the entries in the order they were created.
+	Keys() []string
+}
+
+// Defaulter is implemented by the element types of groups that set their
+// default values themselves. New entries are created with the zero value of
+// the element type and SetDefaults is called on a pointer to it before its
+// fields are turned into settings.
+type Defaulter interface {
+	SetDefaults()
+}
+
+var errGroup = errors.New("groups can only be set by sources supporting them")
+
+// groupValue is the GroupValue of a []T or map[string]T field.
+// The field is replaced by the entries whenever a load finishes.
+type groupValue struct {
+	name    string        // name of the setting
+	field   reflect.Value // the field holding the entries
+	keys    []string
+	entries map[string]*groupEntry
+	secret  bool // whether the settings of the entries are secret
+}
+
+// groupEntry is an entry of a group.
+type groupEntry struct {
+	value    reflect.Value       // pointer to the element
+	settings map[string]*Setting // settings of the element's fields
+}
+
+// isGroup returns whether given type can be set by a group: slices of
+// structs and maps from strings to structs. Structs that are values
+// themselves aren't elements of groups.
+func isGroup(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && (t.Kind() != reflect.Map || t.Key().Kind() != reflect.String) {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() != reflect.Struct {
+		return false
+	}
+	p := reflect.PtrTo(elem)
+	return !p.Implements(reflect.TypeOf((*Value)(nil)).Elem()) &&
+		!p.Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+}
+
+// registerGroup registers the group of given field. Groups are required to have
+// at least one entry, a secret group has secret entries and the rules nonzero,
+// minlen and maxlen apply to the number of entries. It panics on other rules.
+func (c *congo) registerGroup(name string, usage string, field reflect.Value, opts []Option) {
+	g := newGroupValue(name, field)
+	c.Var(g, name, usage, opts...)
+	setting := c.settings[name]
+	for _, rule := range setting.Rules {
+		switch rule.(type) {
+		case nonZeroRule, *lenRule:
+		default:
+			panic(fmt.Sprintf("rule %s isn't supported by group %s", rule, name))
+		}
+	}
+	g.secret = setting.Secret
+}
+
+func newGroupValue(name string, field reflect.Value) *groupValue {
+	g := &groupValue{name: name, field: field}
+	g.reset()
+	return g
+}
+
+// Entry returns the settings of the entry with given key by their names
+// relative to the entry. The entry is created if it doesn't exist yet.
+// The settings are named after the group and the key e.g. "upstream.a.host".
+func (g *groupValue) Entry(key string) map[string]*Setting {
+	if entry, ok := g.entries[key]; ok {
+		return entry.settings
+	}
+	value := reflect.New(g.field.Type().Elem())
+	if defaulter, ok := value.Interface().(Defaulter); ok {
+		defaulter.SetDefaults()
+	}
+	prefix := g.name + NameSeparator + key
+	sub := &congo{settings: make(map[string]*Setting), output: ioutil.Discard}
+	sub.registerStruct(prefix, value.Elem())
+	settings := make(map[string]*Setting, len(sub.settings))
+	for name, setting := range sub.settings {
+		setting.Secret = setting.Secret || g.secret
+		settings[strings.TrimPrefix(name, prefix+NameSeparator)] = setting
+	}
+	g.keys = append(g.keys, key)
+	g.entries[key] = &groupEntry{value, settings}
+	return settings
+}
+
+// lookup returns the setting with given name from settings. Names of the
+// settings of the entries of groups consist of the name of the group, the
+// key of the entry and the name of the setting within the entry.
+func lookup(settings map[string]*Setting, name string) (*Setting, bool) {
+	if setting, ok := settings[name]; ok {
+		return setting, true
+	}
+	for prefix, setting := range settings {
+		group, ok := setting.Value.(*groupValue)
+		if !ok || !strings.HasPrefix(name, prefix+NameSeparator) {
+			continue
+		}
+		rest := name[len(prefix)+len(NameSeparator):]
+		// Keys may contain the separator themselves.
+		for _, key := range group.keys {
+			if strings.HasPrefix(rest, key+NameSeparator) {
+				entry, ok := lookup(group.entries[key].settings, rest[len(key)+len(NameSeparator):])
+				if ok {
+					return entry, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// Keys returns the keys of the entries in the order they were created.
+func (g *groupValue) Keys() []string {
+	return append([]string(nil), g.keys...)
+}
+
+// Set removes all entries if s is empty. Groups can't be set from a string.
+func (g *groupValue) Set(s string) error {
+	if s != "" {
+		return errGroup
+	}
+	g.reset()
+	g.publish()
+	return nil
+}
+
+// String returns the entries as key{name=value,...} in the order they
+// were created.
+func (g *groupValue) String() string {
+	if g == nil {
+		return ""
+	}
+	entries := make([]string, len(g.keys))
+	for i, key := range g.keys {
+		settings := g.entries[key].settings
+		values := make([]string, 0, len(settings))
+		for _, name := range Names(settings) {
+			values = append(values, name+"="+settings[name].Value.String())
+		}
+		entries[i] = key + "{" + strings.Join(values, ",") + "}"
+	}
+	return strings.Join(entries, ",")
+}
+
+// Get returns the field holding the entries.
+func (g *groupValue) Get() interface{} {
+	return g.field.Interface()
+}
+
+// reset removes all entries.
+func (g *groupValue) reset() {
+	g.keys = nil
+	g.entries = make(map[string]*groupEntry)
+}
+
+// save returns a function restoring the current entries and field.
+func (g *groupValue) save() func() {
+	keys, entries, field := g.keys, g.entries, reflect.ValueOf(g.field.Interface())
+	return func() {
+		g.keys, g.entries = keys, entries
+		g.field.Set(field)
+	}
+}
+
+// publish replaces the field by the current entries. The field is replaced
+// instead of modified, so snapshots sharing the previous one aren't affected.
+func (g *groupValue) publish() {
+	t := g.field.Type()
+	if t.Kind() == reflect.Map {
+		m := reflect.MakeMapWithSize(t, len(g.keys))
+		for _, key := range g.keys {
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), g.entries[key].value.Elem())
+		}
+		g.field.Set(m)
+		return
+	}
+	s := reflect.MakeSlice(t, len(g.keys), len(g.keys))
+	for i, key := range g.keys {
+		s.Index(i).Set(g.entries[key].value.Elem())
+	}
+	g.field.Set(s)
+}
+
+// check checks the settings of all entries like the settings of the
+// configuration are checked after loading (see Load). Afterwards the
+// field is replaced by the entries.
+func (g *groupValue) check() error {
+	var errs Errors
+	for _, key := range g.keys {
+		settings := g.entries[key].settings
+		for _, name := range Names(settings) {
+			errs.Append(settings[name].check())
+		}
+	}
+	g.publish()
+	return errs.Err()
+}
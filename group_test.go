@@ -0,0 +1,187 @@
+package congo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+type upstream struct {
+	Host string `name:"host" required:"true"`
+	Port int    `name:"port" min:"1"`
+}
+
+// SetDefaults sets the default port of an upstream.
+func (u *upstream) SetDefaults() {
+	u.Port = 80
+}
+
+// groupSource returns a source setting the entries of the group "upstreams"
+// to given values by their keys.
+func groupSource(entries []map[string]string, keys ...string) *testSource {
+	return &testSource{LoadFunc: func(settings map[string]*Setting) error {
+		var errs Errors
+		group := settings["upstreams"].Value.(GroupValue)
+		for i, key := range keys {
+			entry := group.Entry(key)
+			for name, value := range entries[i] {
+				errs.Append(entry[name].Set(value, Origin{Source: "test"}))
+			}
+		}
+		return errs.Err()
+	}}
+}
+
+func TestUsing_Group(t *testing.T) {
+	config := &struct {
+		Upstreams map[string]upstream `name:"upstreams"`
+		List      []upstream          `name:"list"`
+	}{}
+	src := groupSource([]map[string]string{{"host": "a.example.com"}, {"host": "b.example.com", "port": "8080"}},
+		"a", "b")
+	cfg := New("test", src).Using(config)
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	expected := map[string]upstream{"a": {"a.example.com", 80}, "b": {"b.example.com", 8080}}
+	if !reflect.DeepEqual(config.Upstreams, expected) {
+		t.Errorf("Expected upstreams %v.\nBut got: %v\n", expected, config.Upstreams)
+	}
+	if len(config.List) != 0 {
+		t.Errorf("Expected no list entries.\nBut got: %v\n", config.List)
+	}
+	keys := src.LoadParam["upstreams"].Value.(GroupValue).Keys()
+	if !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("Expected keys [a b].\nBut got: %v\n", keys)
+	}
+}
+
+func TestUsing_GroupSlice(t *testing.T) {
+	config := &struct {
+		Upstreams []upstream `name:"upstreams"`
+	}{}
+	cfg := New("test", groupSource([]map[string]string{{"host": "b"}, {"host": "a"}}, "second", "first"))
+	cfg.Using(config)
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	expected := []upstream{{"b", 80}, {"a", 80}}
+	if !reflect.DeepEqual(config.Upstreams, expected) {
+		t.Errorf("Expected upstreams %v in order of their creation.\nBut got: %v\n", expected, config.Upstreams)
+	}
+}
+
+func TestUsing_GroupValidation(t *testing.T) {
+	config := &struct {
+		Upstreams map[string]upstream `name:"upstreams"`
+	}{}
+	cfg := New("test", groupSource([]map[string]string{{"port": "0"}}, "a")).Using(config)
+	cfg.Init()
+	err := cfg.Load()
+	var settingErr *SettingError
+	if !errors.As(err, &settingErr) || settingErr.Setting != "upstreams.a.host" || !errors.Is(err, ErrRequired) {
+		t.Errorf("Expected upstreams.a.host to be required.\nBut got: %v\n", err)
+	}
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Setting != "upstreams.a.port" || !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected upstreams.a.port to be invalid.\nBut got: %v\n", err)
+	}
+}
+
+func TestReload_Group(t *testing.T) {
+	config := &struct {
+		Upstreams map[string]upstream `name:"upstreams"`
+	}{}
+	fail := false
+	src := &testSource{LoadFunc: func(settings map[string]*Setting) error {
+		entry := settings["upstreams"].Value.(GroupValue).Entry("a")
+		entry["host"].Set("a.example.com", Origin{Source: "test"})
+		if fail {
+			settings["upstreams"].Value.(GroupValue).Entry("b")
+			return errors.New("failed")
+		}
+		return nil
+	}}
+	var changes []Change
+	cfg := New("test", src).Using(config).OnChange(func(c []Change) {
+		changes = c
+	})
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	var snapshot struct {
+		Upstreams map[string]upstream `name:"upstreams"`
+	}
+	cfg.Snapshot(&snapshot)
+
+	fail = true
+	if err := cfg.Reload(); err == nil {
+		t.Fatalf("Expected the reload to fail.\n")
+	}
+	expected := map[string]upstream{"a": {"a.example.com", 80}}
+	if !reflect.DeepEqual(config.Upstreams, expected) {
+		t.Errorf("Expected upstreams to be restored to %v.\nBut got: %v\n", expected, config.Upstreams)
+	}
+
+	fail = false
+	src.LoadFunc = func(settings map[string]*Setting) error {
+		return settings["upstreams"].Value.(GroupValue).Entry("c")["host"].Set("c", Origin{Source: "test"})
+	}
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Expected to reload without problems.\nBut got error: %s\n", err)
+	}
+	if len(changes) != 1 || changes[0].Name != "upstreams" {
+		t.Errorf("Expected upstreams to change.\nBut got: %v\n", changes)
+	}
+	if !reflect.DeepEqual(snapshot.Upstreams, expected) {
+		t.Errorf("Expected the snapshot to be unaffected.\nBut got: %v\n", snapshot.Upstreams)
+	}
+}
+
+func TestUsing_GroupOptions(t *testing.T) {
+	config := &struct {
+		Upstreams map[string]upstream `name:"upstreams" required:"true" secret:"true"`
+		List      []upstream          `name:"list" maxlen:"1"`
+	}{}
+	src := &testSource{LoadFunc: func(settings map[string]*Setting) error {
+		list := settings["list"].Value.(GroupValue)
+		list.Entry("a")["host"].Set("a", Origin{Source: "test"})
+		list.Entry("b")["host"].Set("b", Origin{Source: "test"})
+		return nil
+	}}
+	cfg := New("test", src).Using(config)
+	cfg.Init()
+	err := cfg.Load()
+	var settingErr *SettingError
+	if !errors.As(err, &settingErr) || settingErr.Setting != "upstreams" || !errors.Is(err, ErrRequired) {
+		t.Errorf("Expected upstreams to require an entry.\nBut got: %v\n", err)
+	}
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || loadErr.Setting != "list" || !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected list to violate maxlen=1.\nBut got: %v\n", err)
+	}
+	entry := src.LoadParam["upstreams"].Value.(GroupValue).Entry("a")
+	if !entry["host"].Secret || !entry["port"].Secret {
+		t.Errorf("Expected the settings of the entries of a secret group to be secret.\n")
+	}
+}
+
+func TestUsing_GroupUnsupportedRule(t *testing.T) {
+	defer testForPanic(t)
+	New("test").Using(&struct {
+		Upstreams []upstream `name:"upstreams" min:"1"`
+	}{})
+}
@@ -0,0 +1,83 @@
+// Package input provides the input of sources reading configuration files.
+package input
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Input is the input a source reads from: a file, its content or a reader.
+// Files are read again on every load. Readers can only be read once,
+// so their content is kept after the first read.
+type Input struct {
+	source interface{} // path, content or reader
+	data   []byte      // content of a reader after it was read
+	read   bool        // whether the reader was already read
+}
+
+// New creates an input reading from given source which must be a path
+// (string), the content itself ([]byte) or an io.Reader.
+func New(source interface{}) *Input {
+	return &Input{source: source}
+}
+
+// Name returns the name of the input used to describe locations in it.
+func (in *Input) Name() string {
+	switch source := in.source.(type) {
+	case string:
+		return source
+	case []byte:
+		return "<bytes>"
+	default:
+		return "<reader>"
+	}
+}
+
+// Path returns the path of the file the input reads from.
+// Returns false if the input isn't a file.
+func (in *Input) Path() (string, bool) {
+	path, ok := in.source.(string)
+	return path, ok
+}
+
+// Content returns the content of the input. If loose is set a file that
+// doesn't exist is treated as empty. Readers are closed after they were
+// read if they implement io.Closer.
+func (in *Input) Content(loose bool) ([]byte, error) {
+	switch source := in.source.(type) {
+	case string:
+		data, err := ioutil.ReadFile(source)
+		if err != nil && loose && os.IsNotExist(err) {
+			return []byte{}, nil
+		}
+		return data, err
+	case []byte:
+		return source, nil
+	case io.Reader:
+		if !in.read {
+			data, err := ioutil.ReadAll(source)
+			if err != nil {
+				return nil, err
+			}
+			if closer, ok := source.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					return nil, err
+				}
+			}
+			in.data, in.read = data, true
+		}
+		return in.data, nil
+	default:
+		return nil, fmt.Errorf("unsupported input type %T", source)
+	}
+}
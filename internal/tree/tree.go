@@ -0,0 +1,251 @@
+// Package tree provides the hierarchical documents of sources reading
+// structured formats e.g. JSON, and sets settings from them.
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Scalar is a single value of a document in the string form Value.Set expects.
+type Scalar struct {
+	Raw      string // value as text
+	Location string // where the value is defined e.g. "config.json:3"
+}
+
+// Node is a node of a document. Objects (tables, mappings) have children,
+// all other nodes hold values. Arrays may hold any number of values,
+// empty nodes that aren't arrays stand for null.
+type Node struct {
+	Children map[string]*Node // children of an object; nil for values
+	Values   []Scalar         // values of the node
+	List     bool             // whether the values form an array
+	Location string           // where the node is defined
+}
+
+// NewObject creates an object node without children.
+func NewObject(location string) *Node {
+	return &Node{Children: make(map[string]*Node), Location: location}
+}
+
+// Lookup returns the node at given path of names separated by
+// congo.NameSeparator. Returns nil if there is no such node.
+func (n *Node) Lookup(path string) *Node {
+	if path == "" {
+		return n
+	}
+	node := n
+	for _, key := range strings.Split(path, congo.NameSeparator) {
+		if node.Children == nil {
+			return nil
+		}
+		node = node.Children[key]
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+var (
+	errObject = errors.New("expected a value but got an object")
+	errNested = errors.New("nested objects and arrays are not supported")
+)
+
+// Apply sets all settings found in given document. Arrays are set on
+// settings holding several elements (see congo.SliceValue) element by element
+// and joined by commas for all other settings. Objects can set settings holding
+// maps; their entries are given as key=value. Objects of objects set settings
+// holding groups (see congo.GroupValue) in the order of their keys.
+// Null values are ignored.
+//
+// All settings that can't be set are reported as congo.Errors.
+func Apply(root *Node, source string, settings map[string]*congo.Setting) error {
+	var errs congo.Errors
+	for _, name := range congo.Names(settings) {
+		if node := root.Lookup(name); node != nil {
+			errs.Append(set(settings[name], node, source))
+		}
+	}
+	return errs.Err()
+}
+
+// set sets the setting to the values of given node.
+func set(setting *congo.Setting, node *Node, source string) error {
+	if group, ok := setting.Value.(congo.GroupValue); ok && node.Children != nil {
+		var errs congo.Errors
+		for _, key := range sortedKeys(node) {
+			errs.Append(Apply(node.Children[key], source, group.Entry(key)))
+		}
+		return errs.Err()
+	}
+	_, slice := setting.Value.(congo.SliceValue)
+	values, list := node.Values, node.List
+	if node.Children != nil {
+		if !slice {
+			return &congo.LoadError{Setting: setting.Name, Source: source, Location: node.Location, Err: errObject}
+		}
+		var err error
+		if values, err = entries(node); err != nil {
+			return &congo.LoadError{Setting: setting.Name, Source: source, Location: node.Location, Err: err}
+		}
+		list = true
+	}
+	switch {
+	case !list && len(values) == 0:
+		return nil
+	case !list:
+		return setting.Set(values[0].Raw, congo.Origin{Source: source, Location: values[0].Location})
+	case len(values) == 0:
+		return setting.Set("", congo.Origin{Source: source, Location: node.Location})
+	case !slice:
+		raws := make([]string, len(values))
+		for i, value := range values {
+			raws[i] = value.Raw
+		}
+		return setting.Set(strings.Join(raws, ","), congo.Origin{Source: source, Location: node.Location})
+	}
+	for i, value := range values {
+		origin := congo.Origin{Source: source, Location: value.Location}
+		if i == 0 {
+			if err := setting.Set(value.Raw, origin); err != nil {
+				return err
+			}
+		} else if err := setting.Append(value.Raw, origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entries returns the children of an object as key=value in sorted order.
+func entries(node *Node) ([]Scalar, error) {
+	keys := sortedKeys(node)
+	values := make([]Scalar, 0, len(keys))
+	for _, key := range keys {
+		child := node.Children[key]
+		if child.Children != nil || child.List {
+			return nil, errNested
+		}
+		if len(child.Values) == 1 {
+			values = append(values, Scalar{key + "=" + child.Values[0].Raw, child.Values[0].Location})
+		}
+	}
+	return values, nil
+}
+
+// Template arranges settings hierarchically by their names. Sources use it
+// to write the default values of settings in their format.
+type Template struct {
+	Keys     []string                  // keys of settings and groups in sorted order
+	Settings map[string]*congo.Setting // settings by key
+	Groups   map[string]*Template      // nested templates by key
+}
+
+// NewTemplate arranges given settings. The names of the settings are
+// prefixed with path (e.g. the path a source reads from).
+//
+// Returns an error if the name of a setting is the prefix of another
+// one, because formats can't define both a value and an object with the
+// same key.
+func NewTemplate(settings map[string]*congo.Setting, path string) (*Template, error) {
+	root := newTemplate()
+	for _, name := range congo.Names(settings) {
+		full := name
+		if path != "" {
+			full = path + congo.NameSeparator + name
+		}
+		keys := strings.Split(full, congo.NameSeparator)
+		t := root
+		for _, key := range keys[:len(keys)-1] {
+			if _, ok := t.Settings[key]; ok {
+				return nil, fmt.Errorf("setting %q conflicts with a group of settings", name)
+			}
+			if t.Groups[key] == nil {
+				t.Groups[key] = newTemplate()
+				t.Keys = append(t.Keys, key)
+			}
+			t = t.Groups[key]
+		}
+		key := keys[len(keys)-1]
+		if _, ok := t.Groups[key]; ok {
+			return nil, fmt.Errorf("setting %q conflicts with a group of settings", name)
+		}
+		t.Settings[key] = settings[name]
+		t.Keys = append(t.Keys, key)
+	}
+	root.sort()
+	return root, nil
+}
+
+func newTemplate() *Template {
+	return &Template{Settings: make(map[string]*congo.Setting), Groups: make(map[string]*Template)}
+}
+
+// sort sorts the keys of the template and all nested templates.
+func (t *Template) sort() {
+	sort.Strings(t.Keys)
+	for _, group := range t.Groups {
+		group.sort()
+	}
+}
+
+// Kind is the kind of a value formats distinguish.
+type Kind int
+
+const (
+	// Text values are written as strings.
+	Text Kind = iota
+	// Bool values are booleans.
+	Bool
+	// Number values are integers or floats.
+	Number
+)
+
+// KindOf returns the kind of given value. Values that don't provide their
+// value using Get() or implement fmt.Stringer (e.g. time.Duration) are Text.
+func KindOf(v congo.Value) Kind {
+	getter, ok := v.(interface {
+		Get() interface{}
+	})
+	if !ok {
+		return Text
+	}
+	value := getter.Get()
+	if _, ok := value.(fmt.Stringer); ok {
+		return Text
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool:
+		return Bool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return Number
+	default:
+		return Text
+	}
+}
+
+// sortedKeys returns the keys of the children of an object in sorted order.
+func sortedKeys(node *Node) []string {
+	keys := make([]string, 0, len(node.Children))
+	for key := range node.Children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+package congo
+
+import "fmt"
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Lazy creates a source that is built by given function when the
+// configuration is loaded. This allows sources to depend on settings,
+// like a configuration file whose path is given by a flag:
+//
+//	var path string
+//	cfg := congo.New("app",
+//		flag.New(),
+//		congo.Lazy(func() (congo.Source, error) {
+//			return ini.FromFile(path), nil
+//		}),
+//	)
+//	cfg.StringVar(&path, "config", "/etc/app.ini", "The configuration file")
+//
+// Before a lazy source given to New is built, all other sources are loaded
+// to bootstrap the settings it depends on. Afterwards the lazy source is
+// loaded without overriding the settings of the sources preceding it, so
+// every source is loaded once and the usual order is kept. The source is
+// built on the first successful call of the function only.
+//
+// Lazy sources wrapped by other sources can't be detected. They are built
+// when they are loaded, so they only see the settings of the sources loaded
+// before them.
+func Lazy(build func() (Source, error)) Source {
+	return &lazySource{build: build}
+}
+
+type lazySource struct {
+	build    func() (Source, error)
+	source   Source // nil until built
+	congo    Congo
+	settings map[string]*Setting
+}
+
+// resolve builds the source if it wasn't built yet.
+// The source is attached to the configuration and initialized.
+func (l *lazySource) resolve() error {
+	if l.source != nil {
+		return nil
+	}
+	source, err := l.build()
+	if err != nil {
+		return err
+	}
+	if attacher, ok := source.(Attacher); ok && l.congo != nil {
+		attacher.Attach(l.congo)
+	}
+	if err := source.Init(l.settings); err != nil {
+		return err
+	}
+	l.source = source
+	return nil
+}
+
+// Attach records the configuration the source is attached to once built.
+func (l *lazySource) Attach(c Congo) {
+	l.congo = c
+	if attacher, ok := l.source.(Attacher); ok {
+		attacher.Attach(c)
+	}
+}
+
+// Init records the settings the source is initialized with once built.
+func (l *lazySource) Init(settings map[string]*Setting) error {
+	l.settings = settings
+	if l.source != nil {
+		return l.source.Init(settings)
+	}
+	return nil
+}
+
+// Load builds the source if necessary and loads the settings from it.
+func (l *lazySource) Load(settings map[string]*Setting) error {
+	if err := l.resolve(); err != nil {
+		return err
+	}
+	return l.source.Load(settings)
+}
+
+// Names returns the names the built source knows the setting by.
+func (l *lazySource) Names(setting string) []string {
+	if namer, ok := l.source.(Namer); ok {
+		return namer.Names(setting)
+	}
+	return nil
+}
+
+// bootstrap builds the lazy sources that weren't built yet and loads all
+// sources. The other sources are loaded first, so the lazy sources can depend
+// on their settings. Every source is loaded once: the settings that sources
+// preceding a lazy source in New set are kept when the lazy source is loaded.
+// Returns false without loading anything if there are no such lazy sources.
+// The caller must hold the lock.
+func (c *congo) bootstrap(errs *Errors) bool {
+	var pending []int
+	for i, source := range c.sources {
+		if lazy, ok := source.(*lazySource); ok && lazy.source == nil {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return false
+	}
+	// owners holds the index of the source that set each setting.
+	owners := make(map[string]int, len(c.settings))
+	loaded := func(i int, origins map[string]*Origin) {
+		for name, setting := range c.settings {
+			if setting.Origin != origins[name] {
+				owners[name] = i
+			}
+		}
+	}
+	for i := len(c.sources) - 1; i >= 0; i-- {
+		if lazy, ok := c.sources[i].(*lazySource); ok && lazy.source == nil {
+			continue
+		}
+		origins := c.origins()
+		errs.Append(c.sources[i].Load(c.settings))
+		loaded(i, origins)
+	}
+	for j := len(pending) - 1; j >= 0; j-- {
+		i := pending[j]
+		lazy := c.sources[i].(*lazySource)
+		if err := lazy.resolve(); err != nil {
+			errs.Append(err)
+			continue
+		}
+		var restores []func() error
+		for name, setting := range c.settings {
+			if owner, ok := owners[name]; ok && owner < i {
+				restores = append(restores, keep(setting))
+			}
+		}
+		origins := c.origins()
+		errs.Append(lazy.source.Load(c.settings))
+		for _, restore := range restores {
+			errs.Append(restore())
+		}
+		loaded(i, origins)
+	}
+	return true
+}
+
+// origins returns the current origins of all settings by their names.
+func (c *congo) origins() map[string]*Origin {
+	origins := make(map[string]*Origin, len(c.settings))
+	for name, setting := range c.settings {
+		origins[name] = setting.Origin
+	}
+	return origins
+}
+
+// keep returns a function restoring the current value and origin of given
+// setting (see save).
+func keep(setting *Setting) func() error {
+	restore, origin := save(setting.Value), setting.Origin
+	return func() error {
+		setting.Origin = origin
+		if err := restore(); err != nil {
+			return &SettingError{setting.Name, fmt.Errorf("couldn't restore the value: %w", err)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package congo
+
+import (
+	"errors"
+	"testing"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// setSource returns a source setting the settings to given values.
+func setSource(values map[string]string) *testSource {
+	return &testSource{LoadFunc: func(settings map[string]*Setting) error {
+		var errs Errors
+		for name, value := range values {
+			if setting, ok := settings[name]; ok {
+				errs.Append(setting.Set(value, Origin{Source: "test"}))
+			}
+		}
+		return errs.Err()
+	}}
+}
+
+func TestLazy(t *testing.T) {
+	var path string
+	builds := 0
+	var built *testSource
+	cfg := New("test",
+		setSource(map[string]string{"config": "/etc/app.ini"}),
+		Lazy(func() (Source, error) {
+			builds++
+			built = setSource(map[string]string{"path": path, "config": "ignored"})
+			return built, nil
+		}),
+	)
+	cfg.StringVar(&path, "config", "./app.ini", "")
+	loaded := cfg.String("path", "", "")
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *loaded != "/etc/app.ini" {
+		t.Errorf("Expected the lazy source to be built with the bootstrapped path.\nBut got: %q\n", *loaded)
+	}
+	if path != "/etc/app.ini" {
+		t.Errorf("Expected earlier sources to be preferred.\nBut got: %q\n", path)
+	}
+	if built.InitParam == nil {
+		t.Errorf("Expected the built source to be initialized.\n")
+	}
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Expected to reload without problems.\nBut got error: %s\n", err)
+	}
+	if builds != 1 {
+		t.Errorf("Expected the source to be built once.\nBut was built %d times.\n", builds)
+	}
+}
+
+func TestLazy_LoadOnce(t *testing.T) {
+	loads := 0
+	first := setSource(map[string]string{"name": "first"})
+	load := first.LoadFunc
+	first.LoadFunc = func(settings map[string]*Setting) error {
+		loads++
+		return load(settings)
+	}
+	cfg := New("test",
+		first,
+		Lazy(func() (Source, error) {
+			return setSource(map[string]string{"name": "lazy", "other": "lazy"}), nil
+		}),
+		setSource(map[string]string{"other": "last"}),
+	)
+	name := cfg.String("name", "", "")
+	other := cfg.String("other", "", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if loads != 1 {
+		t.Errorf("Expected the first source to be loaded once.\nBut was loaded %d times.\n", loads)
+	}
+	if *name != "first" || *other != "lazy" {
+		t.Errorf("Expected name first and other lazy.\nBut got: %s and %s\n", *name, *other)
+	}
+	if origin, _ := cfg.Origin("name"); origin.Raw != "first" {
+		t.Errorf("Expected the origin of the first source.\nBut got: %v\n", origin)
+	}
+}
+
+func TestLazy_Error(t *testing.T) {
+	expected := errors.New("no configuration file")
+	fail := true
+	cfg := New("test", Lazy(func() (Source, error) {
+		if fail {
+			return nil, expected
+		}
+		return setSource(map[string]string{"name": "lazy"}), nil
+	}))
+	name := cfg.String("name", "default", "")
+	cfg.Init()
+	if err := cfg.Load(); !errors.Is(err, expected) {
+		t.Errorf("Expected error %q.\nBut got: %v\n", expected, err)
+	}
+	fail = false
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *name != "lazy" {
+		t.Errorf("Expected the source to be built on the next load.\nBut got: %q\n", *name)
+	}
+}
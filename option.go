@@ -0,0 +1,35 @@
+package congo
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Option further describes a setting. Options can be passed to all methods
+// of Congo that define settings e.g.:
+//
+//	port := cfg.Int("port", 0, "Port to listen on", congo.Required())
+type Option func(*Setting)
+
+// Required marks a setting as required. Loading fails with ErrRequired if
+// no source sets a required setting.
+//
+// When using a struct the tag `required:"true"` has the same effect.
+func Required() Option {
+	return func(s *Setting) {
+		s.Required = true
+	}
+}
+
+// Secret marks a setting as secret e.g. a password. Sources writing the
+// current configuration can redact the values of secret settings.
+//
+// When using a struct the tag `secret:"true"` has the same effect.
+func Secret() Option {
+	return func(s *Setting) {
+		s.Secret = true
+	}
+}
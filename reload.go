@@ -0,0 +1,284 @@
+package congo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Change describes the change of a setting during a reload.
+type Change struct {
+	Name string // name of the setting
+	Old  string // previous value (as text)
+	New  string // current value (as text)
+}
+
+// Trigger triggers reloads of a configuration. A trigger sends on the
+// returned channel whenever the configuration should be reloaded and
+// stops once the context is done.
+type Trigger func(ctx context.Context) <-chan struct{}
+
+// OnSignal returns a trigger that fires whenever the process receives
+// one of the given signals. Without signals it fires on syscall.SIGHUP,
+// it never relays all signals like signal.Notify would.
+func OnSignal(signals ...os.Signal) Trigger {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	return func(ctx context.Context) <-chan struct{} {
+		received := make(chan os.Signal, 1)
+		signal.Notify(received, signals...)
+		fire := make(chan struct{})
+		go func() {
+			defer signal.Stop(received)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-received:
+					send(ctx, fire)
+				}
+			}
+		}()
+		return fire
+	}
+}
+
+// fileState is the state of a file used to detect changes.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// stat returns the current state of the file at given path.
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{true, info.Size(), info.ModTime()}
+}
+
+// OnFileChange returns a trigger that fires whenever one of the files
+// at given paths is created, modified or removed. The files are checked
+// for changes in the given interval.
+func OnFileChange(interval time.Duration, paths ...string) Trigger {
+	return func(ctx context.Context) <-chan struct{} {
+		states := make(map[string]fileState, len(paths))
+		for _, path := range paths {
+			states[path] = stat(path)
+		}
+		fire := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				changed := false
+				for _, path := range paths {
+					state := stat(path)
+					changed = changed || state != states[path]
+					states[path] = state
+				}
+				if changed {
+					send(ctx, fire)
+				}
+			}
+		}()
+		return fire
+	}
+}
+
+// send sends on fire unless the context is done first.
+func send(ctx context.Context, fire chan<- struct{}) {
+	select {
+	case fire <- struct{}{}:
+	case <-ctx.Done():
+	}
+}
+
+// state is the state of a setting that is restored if a reload fails.
+type state struct {
+	value  string
+	origin *Origin
+}
+
+// Reload loads the configuration from the sources again. Settings no source
+// sets anymore fall back to their default value.
+// If an error occurs all settings keep their previous values and the error is
+// returned. Otherwise the listeners registered with OnChange() are notified
+// about the settings that changed.
+func (c *congo) Reload() error {
+	changes, err := c.reload()
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		c.mu.RLock()
+		listeners := c.listeners
+		c.mu.RUnlock()
+		for _, listener := range listeners {
+			listener(changes)
+		}
+	}
+	return nil
+}
+
+// reload reloads all settings and returns the changes. Listeners aren't
+// notified, so they can access the configuration.
+func (c *congo) reload() ([]Change, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := make(map[string]state, len(c.settings))
+	restores := make(map[string]func() error, len(c.settings))
+	for name, setting := range c.settings {
+		previous[name] = state{setting.Value.String(), setting.Origin}
+		restores[name] = save(setting.Value)
+		// Values are reset, so settings that were removed from a source
+		// don't keep the value they had before. Errors are ignored, since
+		// the default was formatted by the value itself and can be set again.
+		setting.Value.Set(setting.DefValue)
+	}
+	if err := c.load(); err != nil {
+		var errs Errors
+		errs.Append(err)
+		for _, name := range Names(c.settings) {
+			if err := restores[name](); err != nil {
+				errs.Append(&SettingError{name, fmt.Errorf("couldn't restore the previous value: %w", err)})
+			}
+			c.settings[name].Origin = previous[name].origin
+		}
+		return nil, errs.Err()
+	}
+	var changes []Change
+	for _, name := range Names(c.settings) {
+		if current := c.settings[name].Value.String(); current != previous[name].value {
+			changes = append(changes, Change{name, previous[name].value, current})
+		}
+	}
+	return changes, nil
+}
+
+// OnChange registers a listener that is called with the settings that
+// changed whenever Reload() changes any setting.
+//
+// Returns itself so calls can be chained.
+func (c *congo) OnChange(listener func([]Change)) Congo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+	return c
+}
+
+// Watch reloads the configuration whenever one of the triggers fires until
+// the context is done. Errors of reloads are written to the output of the
+// configuration (os.Stderr).
+//
+// Returns the error of the context.
+func (c *congo) Watch(ctx context.Context, triggers ...Trigger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fired := make(chan struct{})
+	for _, trigger := range triggers {
+		go func(fire <-chan struct{}) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-fire:
+					send(ctx, fired)
+				}
+			}
+		}(trigger(ctx))
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-fired:
+			if err := c.Reload(); err != nil {
+				fmt.Fprintf(c.output, "%s: reload failed: %s\n", c.name, err)
+			}
+		}
+	}
+}
+
+// save returns a function restoring the current state of given value.
+// The values of this package are saved directly, since they replace their
+// state instead of modifying it when set. All other values are restored
+// from their string representation, which may fail.
+func save(v Value) func() error {
+	switch a := v.(type) {
+	case *groupValue:
+		return restoring(a.save())
+	case *boolValue:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *intValue:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *int64Value:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *uintValue:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *uint64Value:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *stringValue:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *float64Value:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *durationValue:
+		saved := *a
+		return restoring(func() { *a = saved })
+	case *stringSliceValue:
+		saved := *a.p
+		return restoring(func() { *a.p = saved })
+	case *intSliceValue:
+		saved := *a.p
+		return restoring(func() { *a.p = saved })
+	case *durationSliceValue:
+		saved := *a.p
+		return restoring(func() { *a.p = saved })
+	case *stringMapValue:
+		saved := *a.p
+		return restoring(func() { *a.p = saved })
+	case *textPointerValue:
+		saved := reflect.ValueOf(a.p.Interface())
+		return restoring(func() { a.p.Set(saved) })
+	default:
+		text := v.String()
+		return func() error {
+			return v.Set(text)
+		}
+	}
+}
+
+// restoring returns a function calling restore that never fails.
+func restoring(restore func()) func() error {
+	return func() error {
+		restore()
+		return nil
+	}
+}
@@ -0,0 +1,187 @@
+package congo
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+func TestCongo_Reload(t *testing.T) {
+	c, s := setupTestCongo()
+	number := c.Int("number", 1, "")
+	text := c.String("text", "default", "")
+	values := map[string]string{"number": "2", "text": "first"}
+	s.LoadFunc = func(settings map[string]*Setting) error {
+		var errs Errors
+		for name, value := range values {
+			errs.Append(settings[name].Set(value, Origin{Source: "test"}))
+		}
+		return errs.Err()
+	}
+	var changes []Change
+	c.OnChange(func(c []Change) {
+		changes = c
+	})
+	c.Init()
+	c.Load()
+
+	// Removed settings fall back to their default
+	values = map[string]string{"number": "3"}
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Expected to reload without problems.\nBut got error: %s\n", err)
+	}
+	expected := []Change{{"number", "2", "3"}, {"text", "first", "default"}}
+	if len(changes) != 2 || changes[0] != expected[0] || changes[1] != expected[1] {
+		t.Errorf("Expected changes to be %v.\nBut got: %v\n", expected, changes)
+	}
+	if *number != 3 || *text != "default" || c.IsSet("text") {
+		t.Errorf("Expected values to be reloaded.\nBut got: %d %q\n", *number, *text)
+	}
+
+	// Failed reloads keep the previous values
+	changes = nil
+	values = map[string]string{"number": "invalid", "text": "second"}
+	if err := c.Reload(); err == nil {
+		t.Errorf("Expected invalid value to cause an error.\nBut no error was returned.\n")
+	}
+	if *number != 3 || *text != "default" || !c.IsSet("number") || c.IsSet("text") {
+		t.Errorf("Expected previous values to be kept.\nBut got: %d %q\n", *number, *text)
+	}
+	if changes != nil {
+		t.Errorf("Expected listeners not to be notified.\nBut got: %v\n", changes)
+	}
+}
+
+// strictValue is a value that can't be set from its string representation.
+type strictValue struct {
+	n int
+}
+
+func (v *strictValue) Set(s string) error {
+	if !strings.HasPrefix(s, "n=") {
+		return errors.New("expected n=<number>")
+	}
+	n, err := strconv.Atoi(s[2:])
+	v.n = n
+	return err
+}
+
+func (v *strictValue) String() string { return strconv.Itoa(v.n) }
+
+// TestCongo_Reload_Restore tests that failed reloads restore values that
+// can't be formatted and parsed again without losing their state.
+func TestCongo_Reload_Restore(t *testing.T) {
+	c, s := setupTestCongo()
+	tags := c.StringSlice("tags", nil, "")
+	strict := &strictValue{}
+	c.Var(strict, "strict", "")
+	fail := false
+	s.LoadFunc = func(settings map[string]*Setting) error {
+		if fail {
+			settings["tags"].Set("changed", Origin{Source: "test"})
+			settings["strict"].Set("n=2", Origin{Source: "test"})
+			return errors.New("failed")
+		}
+		// An element containing the separator e.g. from a JSON array.
+		*settings["tags"].Value.(*stringSliceValue).p = []string{"a,b"}
+		return settings["strict"].Set("n=1", Origin{Source: "test"})
+	}
+	c.Init()
+	if err := c.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+
+	fail = true
+	err := c.Reload()
+	if !reflect.DeepEqual(*tags, []string{"a,b"}) {
+		t.Errorf("Expected tags [a,b] to be restored.\nBut got: %q\n", *tags)
+	}
+	var settingErr *SettingError
+	if !errors.As(err, &settingErr) || settingErr.Setting != "strict" {
+		t.Errorf("Expected the failure to restore strict to be reported.\nBut got: %v\n", err)
+	}
+}
+
+func TestOnSignal_Default(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fire := OnSignal()(ctx)
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("Couldn't send SIGHUP: %s", err)
+	}
+	select {
+	case <-fire:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the trigger to fire on SIGHUP.\n")
+	}
+}
+
+func TestCongo_Watch(t *testing.T) {
+	c, s := setupTestCongo()
+	loads := make(chan struct{})
+	s.LoadFunc = func(map[string]*Setting) error {
+		loads <- struct{}{}
+		return nil
+	}
+	fire := make(chan struct{})
+	trigger := func(context.Context) <-chan struct{} { return fire }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- c.Watch(ctx, trigger)
+	}()
+	fire <- struct{}{}
+	select {
+	case <-loads:
+	case <-time.After(time.Second):
+		t.Errorf("Expected trigger to cause a reload.\n")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected watch to return the error of the context.\nBut got: %v\n", err)
+	}
+}
+
+func TestOnFileChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "congo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.ini")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fire := OnFileChange(time.Millisecond, path)(ctx)
+	select {
+	case <-fire:
+		t.Errorf("Expected trigger not to fire without changes.\n")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := ioutil.WriteFile(path, []byte("number=5"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-fire:
+	case <-time.After(time.Second):
+		t.Errorf("Expected trigger to fire when the file is created.\n")
+	}
+}
@@ -0,0 +1,89 @@
+package congo
+
+import (
+	"encoding"
+	"reflect"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Read calls fn while no source changes the values of the settings.
+// Variables and structs linked to settings must only be accessed within
+// Read (or using Snapshot) while the configuration is reloaded concurrently.
+//
+// Other methods of the configuration must not be called within fn.
+func (c *congo) Read(fn func()) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fn()
+}
+
+// Snapshot copies a struct given to Using() into the struct dst points to.
+// The copy is consistent: it contains the values of a single load and
+// isn't affected by later loads.
+// Values of custom types that are referenced by pointers or interfaces
+// are shared between the copy and the original. Fields set using
+// UnmarshalText (e.g. a big.Int) are copied using MarshalText.
+//
+// Snapshot panics if dst isn't a pointer to the type of a struct given to Using().
+func (c *congo) Snapshot(dst interface{}) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic("Snapshot only supports non-nil pointers to structs given to Using.")
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, s := range c.structs {
+		if s.Type() == v.Type() {
+			// Values of settings never modify slices, maps or pointed to values
+			// in place, so a shallow copy isn't affected by later loads.
+			// Only UnmarshalText may modify the memory of a value in place.
+			v.Elem().Set(s.Elem())
+			copyTexts(v.Elem())
+			return
+		}
+	}
+	panic("Snapshot only supports pointers to the type of a struct given to Using. " +
+		"No struct of type " + v.Type().Elem().String() + " was given.")
+}
+
+// copyTexts replaces the fields of given struct that are set using
+// UnmarshalText by copies. UnmarshalText may reuse the memory referenced
+// by a value (e.g. big.Int does), so a shallow copy would be changed by
+// later loads. Fields that can't be formatted using MarshalText are kept.
+func copyTexts(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		f := v.Field(i)
+		p := f.Addr().Interface()
+		if _, ok := p.(Value); ok {
+			continue
+		}
+		if _, ok := p.(encoding.TextUnmarshaler); !ok {
+			if f.Kind() == reflect.Struct {
+				copyTexts(f)
+			}
+			continue
+		}
+		marshaler, ok := p.(encoding.TextMarshaler)
+		if !ok {
+			continue
+		}
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			continue
+		}
+		fresh := reflect.New(f.Type())
+		if fresh.Interface().(encoding.TextUnmarshaler).UnmarshalText(text) == nil {
+			f.Set(fresh.Elem())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package congo
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+type testSnapshotStruct struct {
+	A    int
+	B    string
+	Tags []string
+	Big  *big.Int
+}
+
+// setupCountingCongo creates a configuration whose source sets all
+// settings to the number of times it was loaded.
+func setupCountingCongo() (Congo, *testSnapshotStruct, *int) {
+	c, s := setupTestCongo()
+	settings := &testSnapshotStruct{Big: big.NewInt(0)}
+	c.Using(settings)
+	counter := c.Int("counter", 0, "")
+	loads := 0
+	s.LoadFunc = func(settings map[string]*Setting) error {
+		loads++
+		n := strconv.Itoa(loads)
+		origin := Origin{Source: "test"}
+		var errs Errors
+		errs.Append(settings["A"].Set(n, origin))
+		errs.Append(settings["B"].Set(n, origin))
+		errs.Append(settings["Tags"].Set(n, origin))
+		errs.Append(settings["Tags"].Append(n, origin))
+		errs.Append(settings["Big"].Set(n, origin))
+		errs.Append(settings["counter"].Set(n, origin))
+		return errs.Err()
+	}
+	c.Init()
+	c.Load()
+	return c, settings, counter
+}
+
+// TestCongo_Snapshot tests that snapshots are consistent while the
+// configuration is reloaded. Run with -race to detect data races.
+func TestCongo_Snapshot(t *testing.T) {
+	c, _, counter := setupCountingCongo()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := c.Reload(); err != nil {
+				t.Errorf("Expected to reload without problems.\nBut got error: %s\n", err)
+			}
+		}
+	}()
+	for reloading := true; reloading; {
+		select {
+		case <-done:
+			reloading = false
+		default:
+		}
+		var snapshot testSnapshotStruct
+		c.Snapshot(&snapshot)
+		n := strconv.Itoa(snapshot.A)
+		if snapshot.B != n || len(snapshot.Tags) != 2 || snapshot.Tags[1] != n ||
+			snapshot.Big.String() != n {
+			t.Errorf("Expected snapshot to be consistent.\nBut got: %+v\n", snapshot)
+		}
+		// Changing the snapshot must not affect the configuration.
+		snapshot.Tags = append(snapshot.Tags, "changed")
+
+		c.Read(func() {
+			if *counter < snapshot.A {
+				t.Errorf("Expected counter to be at least %d.\nBut was %d.\n", snapshot.A, *counter)
+			}
+		})
+	}
+}
+
+// TestCongo_Snapshot_Text tests that values set using UnmarshalText in place
+// aren't shared between snapshots and the configuration.
+func TestCongo_Snapshot_Text(t *testing.T) {
+	config := &struct {
+		N big.Int `name:"n"`
+	}{}
+	value := "12345678901234567890123"
+	c := New("test", &testSource{LoadFunc: func(settings map[string]*Setting) error {
+		return settings["n"].Set(value, Origin{Source: "test"})
+	}}).Using(config)
+	c.Init()
+	if err := c.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	var snapshot struct {
+		N big.Int `name:"n"`
+	}
+	c.Snapshot(&snapshot)
+
+	expected := value
+	value = "98765432109876543210987"
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Expected to reload without problems.\nBut got error: %s\n", err)
+	}
+	if config.N.String() != value {
+		t.Errorf("Expected n to be reloaded as %s.\nBut got: %s\n", value, config.N.String())
+	}
+	if snapshot.N.String() != expected {
+		t.Errorf("Expected the snapshot to keep %s.\nBut got: %s\n", expected, snapshot.N.String())
+	}
+}
+
+func TestCongo_Snapshot_Unknown(t *testing.T) {
+	defer testForPanic(t)
+	c, _, _ := setupCountingCongo()
+	var unknown struct{ A int }
+	c.Snapshot(&unknown)
+}
@@ -0,0 +1,270 @@
+// Package combinator provides sources wrapping other sources to change
+// which settings they see and how they are named, or how their errors
+// are handled.
+//
+// All wrappers forward Init and Load as well as the optional interfaces
+// congo.Namer and congo.Attacher to the wrapped sources.
+package combinator
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Prefix returns a source that passes all settings to given source with
+// given prefix added to their names e.g. Prefix("app.", src) makes the
+// setting "port" known as "app.port" to src.
+func Prefix(prefix string, source congo.Source) congo.Source {
+	return &mapped{source, func(name string) (string, bool) {
+		return prefix + name, true
+	}}
+}
+
+// StripPrefix returns a source that only passes the settings whose names
+// start with given prefix to given source and removes the prefix from their
+// names e.g. StripPrefix("db.", src) makes the setting "db.host" known as
+// "host" to src and hides the setting "port" from it.
+func StripPrefix(prefix string, source congo.Source) congo.Source {
+	return &mapped{source, func(name string) (string, bool) {
+		if !strings.HasPrefix(name, prefix) {
+			return "", false
+		}
+		return name[len(prefix):], true
+	}}
+}
+
+// Rename returns a source that passes all settings to given source and
+// renames the ones contained in given map to the names they are mapped to
+// e.g. Rename(map[string]string{"max-users": "maxUsers"}, src) makes the
+// setting "max-users" known as "maxUsers" to src.
+func Rename(names map[string]string, source congo.Source) congo.Source {
+	return &mapped{source, func(name string) (string, bool) {
+		if renamed, ok := names[name]; ok {
+			return renamed, true
+		}
+		return name, true
+	}}
+}
+
+// Filter returns a source that only passes the settings given function
+// returns true for to given source.
+func Filter(keep func(name string) bool, source congo.Source) congo.Source {
+	return &mapped{source, func(name string) (string, bool) {
+		return name, keep(name)
+	}}
+}
+
+// mapped is a source passing the settings to another source under
+// different names.
+type mapped struct {
+	source congo.Source
+	// name returns the name of the setting with given name in the source
+	// and whether the setting is passed to the source at all.
+	name func(setting string) (string, bool)
+}
+
+// Init initializes the wrapped source with the settings passed to it.
+func (m *mapped) Init(settings map[string]*congo.Setting) error {
+	translated, err := m.translate(settings)
+	if err != nil {
+		return err
+	}
+	return m.source.Init(translated)
+}
+
+// Load loads the settings passed to the wrapped source.
+func (m *mapped) Load(settings map[string]*congo.Setting) error {
+	translated, err := m.translate(settings)
+	if err != nil {
+		return err
+	}
+	return m.source.Load(translated)
+}
+
+// translate returns the settings passed to the wrapped source by their
+// names in the source. Returns an error if several settings have the same
+// name in the source.
+func (m *mapped) translate(settings map[string]*congo.Setting) (map[string]*congo.Setting, error) {
+	translated := make(map[string]*congo.Setting, len(settings))
+	origins := make(map[string]string, len(settings))
+	for _, name := range congo.Names(settings) {
+		renamed, ok := m.name(name)
+		if !ok {
+			continue
+		}
+		if other, ok := origins[renamed]; ok {
+			return nil, fmt.Errorf("combinator: the settings %q and %q are both named %q", other, name, renamed)
+		}
+		translated[renamed] = settings[name]
+		origins[renamed] = name
+	}
+	return translated, nil
+}
+
+// Names returns the names the wrapped source knows the setting by.
+func (m *mapped) Names(setting string) []string {
+	renamed, ok := m.name(setting)
+	if !ok {
+		return nil
+	}
+	return names(m.source, renamed)
+}
+
+// Attach attaches the wrapped source to the configuration.
+func (m *mapped) Attach(c congo.Congo) {
+	attach(m.source, c)
+}
+
+// Optional returns a source that ignores all errors of given source.
+// Settings the source managed to set before failing keep their values.
+func Optional(source congo.Source) congo.Source {
+	return &optional{source}
+}
+
+type optional struct {
+	source congo.Source
+}
+
+// Init initializes the wrapped source ignoring any error.
+func (o *optional) Init(settings map[string]*congo.Setting) error {
+	o.source.Init(settings)
+	return nil
+}
+
+// Load loads the settings from the wrapped source ignoring any error.
+func (o *optional) Load(settings map[string]*congo.Setting) error {
+	o.source.Load(settings)
+	return nil
+}
+
+// Names returns the names the wrapped source knows the setting by.
+func (o *optional) Names(setting string) []string {
+	return names(o.source, setting)
+}
+
+// Attach attaches the wrapped source to the configuration.
+func (o *optional) Attach(c congo.Congo) {
+	attach(o.source, c)
+}
+
+// Fallback returns a source that loads the settings from the primary source
+// and all settings the primary source doesn't set from the fallback source.
+// The errors of both sources are reported.
+func Fallback(primary, fallback congo.Source) congo.Source {
+	return &fallbackSource{primary, fallback}
+}
+
+type fallbackSource struct {
+	primary  congo.Source
+	fallback congo.Source
+}
+
+// Init initializes both sources.
+func (f *fallbackSource) Init(settings map[string]*congo.Setting) error {
+	var errs congo.Errors
+	errs.Append(f.primary.Init(settings))
+	errs.Append(f.fallback.Init(settings))
+	return errs.Err()
+}
+
+// Load loads the settings from the primary source and the settings
+// the primary source left untouched from the fallback source.
+func (f *fallbackSource) Load(settings map[string]*congo.Setting) error {
+	origins := make(map[string]*congo.Origin, len(settings))
+	for name, setting := range settings {
+		origins[name] = setting.Origin
+	}
+	var errs congo.Errors
+	errs.Append(f.primary.Load(settings))
+	unset := make(map[string]*congo.Setting, len(settings))
+	for name, setting := range settings {
+		if setting.Origin == origins[name] {
+			unset[name] = setting
+		}
+	}
+	errs.Append(f.fallback.Load(unset))
+	return errs.Err()
+}
+
+// Names returns the names both sources know the setting by.
+func (f *fallbackSource) Names(setting string) []string {
+	return append(names(f.primary, setting), names(f.fallback, setting)...)
+}
+
+// Attach attaches both sources to the configuration.
+func (f *fallbackSource) Attach(c congo.Congo) {
+	attach(f.primary, c)
+	attach(f.fallback, c)
+}
+
+// Merge returns a source that loads the settings from all given sources
+// just as if the sources were given to congo.New: the sources are loaded
+// in reverse order, so earlier sources override the settings of later ones.
+// The errors of all sources are reported.
+func Merge(sources ...congo.Source) congo.Source {
+	return merged(sources)
+}
+
+type merged []congo.Source
+
+// Init initializes all sources in reverse order.
+func (m merged) Init(settings map[string]*congo.Setting) error {
+	var errs congo.Errors
+	for i := len(m) - 1; i >= 0; i-- {
+		errs.Append(m[i].Init(settings))
+	}
+	return errs.Err()
+}
+
+// Load loads the settings from all sources in reverse order.
+func (m merged) Load(settings map[string]*congo.Setting) error {
+	var errs congo.Errors
+	for i := len(m) - 1; i >= 0; i-- {
+		errs.Append(m[i].Load(settings))
+	}
+	return errs.Err()
+}
+
+// Names returns the names all sources know the setting by
+// with the names of earlier sources first.
+func (m merged) Names(setting string) []string {
+	var all []string
+	for _, source := range m {
+		all = append(all, names(source, setting)...)
+	}
+	return all
+}
+
+// Attach attaches all sources to the configuration.
+func (m merged) Attach(c congo.Congo) {
+	for _, source := range m {
+		attach(source, c)
+	}
+}
+
+// names returns the names given source knows the setting by
+// if it implements congo.Namer.
+func names(source congo.Source, setting string) []string {
+	if namer, ok := source.(congo.Namer); ok {
+		return namer.Names(setting)
+	}
+	return nil
+}
+
+// attach attaches given source to the configuration
+// if it implements congo.Attacher.
+func attach(source congo.Source, c congo.Congo) {
+	if attacher, ok := source.(congo.Attacher); ok {
+		attacher.Attach(c)
+	}
+}
@@ -0,0 +1,172 @@
+package combinator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/mapsource"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// testSource records the settings it is given and knows every setting
+// by its name in upper case.
+type testSource struct {
+	InitParam map[string]*congo.Setting
+	LoadParam map[string]*congo.Setting
+	InitErr   error
+	LoadErr   error
+	Attached  congo.Congo
+}
+
+func (t *testSource) Init(param map[string]*congo.Setting) error {
+	t.InitParam = param
+	return t.InitErr
+}
+
+func (t *testSource) Load(param map[string]*congo.Setting) error {
+	t.LoadParam = param
+	return t.LoadErr
+}
+
+func (t *testSource) Names(setting string) []string {
+	return []string{strings.ToUpper(setting)}
+}
+
+func (t *testSource) Attach(c congo.Congo) {
+	t.Attached = c
+}
+
+func TestMapped(t *testing.T) {
+	rename := map[string]string{"max-users": "maxUsers"}
+	tests := []struct {
+		name     string
+		wrap     func(congo.Source) congo.Source
+		expected []string
+		names    []string // names of "db.host"
+	}{
+		{"Prefix", func(s congo.Source) congo.Source { return Prefix("app.", s) },
+			[]string{"app.db.host", "app.max-users", "app.port"}, []string{"APP.DB.HOST"}},
+		{"StripPrefix", func(s congo.Source) congo.Source { return StripPrefix("db.", s) },
+			[]string{"host"}, []string{"HOST"}},
+		{"Rename", func(s congo.Source) congo.Source { return Rename(rename, s) },
+			[]string{"db.host", "maxUsers", "port"}, []string{"DB.HOST"}},
+		{"Filter", func(s congo.Source) congo.Source {
+			return Filter(func(name string) bool { return name != "db.host" }, s)
+		}, []string{"max-users", "port"}, nil},
+	}
+	for _, test := range tests {
+		src := &testSource{}
+		cfg := congo.New("test", test.wrap(src))
+		cfg.String("db.host", "", "")
+		cfg.Int("max-users", 0, "")
+		cfg.Int("port", 0, "")
+		if err := cfg.Init(); err != nil {
+			t.Fatalf("%s: Expected to init without problems.\nBut got error: %s\n", test.name, err)
+		}
+		if err := cfg.Load(); err != nil {
+			t.Fatalf("%s: Expected to load without problems.\nBut got error: %s\n", test.name, err)
+		}
+		if got := congo.Names(src.InitParam); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: Expected Init() to get %v.\nBut got: %v\n", test.name, test.expected, got)
+		}
+		if got := congo.Names(src.LoadParam); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: Expected Load() to get %v.\nBut got: %v\n", test.name, test.expected, got)
+		}
+		if src.Attached != cfg {
+			t.Errorf("%s: Expected the source to be attached to the configuration.\n", test.name)
+		}
+		if got := test.wrap(src).(congo.Namer).Names("db.host"); !reflect.DeepEqual(got, test.names) {
+			t.Errorf("%s: Expected names %v.\nBut got: %v\n", test.name, test.names, got)
+		}
+	}
+}
+
+func TestStripPrefix_Load(t *testing.T) {
+	cfg := congo.New("test", StripPrefix("db.", mapsource.New(map[string]string{
+		"host":    "example.com",
+		"db.host": "wrong",
+	})))
+	host := cfg.String("db.host", "localhost", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *host != "example.com" {
+		t.Errorf("Expected host example.com.\nBut got: %s\n", *host)
+	}
+}
+
+func TestRename_Conflict(t *testing.T) {
+	cfg := congo.New("test", Rename(map[string]string{"a": "b"}, &testSource{}))
+	cfg.String("a", "", "")
+	cfg.String("b", "", "")
+	if err := cfg.Init(); err == nil || !strings.Contains(err.Error(), `"a" and "b" are both named "b"`) {
+		t.Errorf("Expected an error about the conflicting names.\nBut got: %v\n", err)
+	}
+}
+
+func TestOptional(t *testing.T) {
+	src := &testSource{InitErr: errors.New("init"), LoadErr: errors.New("load")}
+	cfg := congo.New("test", Optional(src))
+	cfg.String("name", "", "")
+	if err := cfg.Init(); err != nil {
+		t.Errorf("Expected init error to be ignored.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected load error to be ignored.\nBut got error: %s\n", err)
+	}
+	if src.LoadParam == nil {
+		t.Errorf("Expected Load() to be forwarded.\n")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	primary := mapsource.New(map[string]string{"port": "eighty", "host": "example.com"})
+	fallback := mapsource.New(map[string]string{"port": "8080", "host": "wrong", "user": "admin"})
+	cfg := congo.New("test", Fallback(primary, fallback))
+	port := cfg.Int("port", 0, "")
+	host := cfg.String("host", "", "")
+	user := cfg.String("user", "", "")
+	cfg.Init()
+	err := cfg.Load()
+	var loadErr *congo.LoadError
+	if !errors.As(err, &loadErr) || loadErr.Setting != "port" {
+		t.Errorf("Expected the error of the primary source for port.\nBut got: %v\n", err)
+	}
+	if *port != 8080 || *host != "example.com" || *user != "admin" {
+		t.Errorf("Expected port 8080, host example.com and user admin.\nBut got: %d, %s and %s\n",
+			*port, *host, *user)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := mapsource.New(map[string]string{"port": "80", "host": "example.com"})
+	second := mapsource.New(map[string]string{"port": "8080"})
+	last := &testSource{LoadErr: errors.New("failed")}
+	cfg := congo.New("test", Merge(first, second, last))
+	port := cfg.Int("port", 0, "")
+	host := cfg.String("host", "", "")
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("Expected the error of the last source.\nBut got: %v\n", err)
+	}
+	if *port != 80 || *host != "example.com" {
+		t.Errorf("Expected port 80 of the first source and host example.com.\nBut got: %d and %s\n", *port, *host)
+	}
+	expected := []string{"PORT"}
+	if names := Merge(first, last).(congo.Namer).Names("port"); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected names %v.\nBut got: %v\n", expected, names)
+	}
+}
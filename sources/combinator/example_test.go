@@ -0,0 +1,43 @@
+package combinator
+
+import (
+	"fmt"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/mapsource"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Example is a basic example for the usage of the combinators.
+func Example() {
+	// A legacy source only knowing the database settings by other names
+	legacy := mapsource.New(map[string]string{
+		"hostname": "db.example.com",
+		"maxConns": "20",
+	})
+
+	// Configuration
+	cfg := congo.New("main", StripPrefix("database.", Rename(map[string]string{
+		"host":      "hostname",
+		"max-conns": "maxConns",
+	}, legacy)))
+
+	host := cfg.String("database.host", "localhost", "The host of the database")
+	maxConns := cfg.Int("database.max-conns", 10, "The maximum number of connections")
+
+	// Load configurations
+	cfg.Init()
+	cfg.Load()
+
+	fmt.Printf("Connecting to %s with up to %d connections\n", *host, *maxConns)
+
+	//Output:
+	//Connecting to db.example.com with up to 20 connections
+}
@@ -0,0 +1,246 @@
+// Package dir provides a source loading settings from a directory containing
+// a file per setting, like mounted Kubernetes ConfigMaps and Secrets or
+// Docker secrets (/run/secrets).
+package dir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/env"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "dir"
+
+// New creates a new directory source which loads settings from the files
+// in the directory at given path. The name of a file is the name of the
+// setting and its content, without surrounding whitespace, the value.
+//
+// Entries whose names start with ".." are skipped, so the data directories
+// Kubernetes creates next to the files aren't read twice. Symbolic links
+// are followed.
+func New(path string) Source {
+	return &source{path: path, translator: env.IdenticalTranslator, looseLoad: true}
+}
+
+// Source is a source that loads settings from the files in a directory.
+type Source interface {
+	congo.Source
+	// WithTranslator adds a translator function that translates the name of
+	// a setting into the names of the files that may contain its value
+	// (e.g. env.PrefixSdtTranslator("app_") for Docker secrets named like
+	// environment variables). Names given first are preferred.
+	WithTranslator(t env.Translator) Source
+	// SetRecursive sets whether subdirectories are read as well. The files
+	// in subdirectories set settings with hierarchical names: the file
+	// "database/password" has the name "database.password". Default is false.
+	SetRecursive(recursive bool) Source
+	// SetLooseLoad sets whether this source should complain if the directory
+	// doesn't exist. Default is true.
+	SetLooseLoad(loose bool) Source
+}
+
+type source struct {
+	path       string
+	translator env.Translator
+	recursive  bool
+	looseLoad  bool
+	groups     map[string]bool // names of the groups, which can't be set by files
+}
+
+// WithTranslator adds a translator function that translates the name of
+// a setting into the names of the files that may contain its value
+// (e.g. env.PrefixSdtTranslator("app_") for Docker secrets named like
+// environment variables). Names given first are preferred.
+func (s *source) WithTranslator(t env.Translator) Source {
+	s.translator = t
+	return s
+}
+
+// SetRecursive sets whether subdirectories are read as well. The files
+// in subdirectories set settings with hierarchical names: the file
+// "database/password" has the name "database.password". Default is false.
+func (s *source) SetRecursive(recursive bool) Source {
+	s.recursive = recursive
+	return s
+}
+
+// SetLooseLoad sets whether this source should complain if the directory
+// doesn't exist. Default is true.
+func (s *source) SetLooseLoad(loose bool) Source {
+	s.looseLoad = loose
+	return s
+}
+
+// Names returns the paths of the files that set the setting with given name.
+// Groups (see congo.GroupValue) aren't set by any file.
+func (s *source) Names(setting string) []string {
+	if s.groups[setting] {
+		return nil
+	}
+	var names []string
+	for _, alternative := range s.translator(setting) {
+		names = append(names, s.file(alternative))
+	}
+	return names
+}
+
+// file returns the path of the file with given name.
+func (s *source) file(name string) string {
+	if s.recursive {
+		name = filepath.Join(strings.Split(name, congo.NameSeparator)...)
+	}
+	return filepath.Join(s.path, name)
+}
+
+// Init records the groups among given settings, so they are skipped.
+func (s *source) Init(settings map[string]*congo.Setting) error {
+	s.groups = make(map[string]bool)
+	for name, setting := range settings {
+		if _, ok := setting.Value.(congo.GroupValue); ok {
+			s.groups[name] = true
+		}
+	}
+	return nil
+}
+
+// Load loads the settings from the files in the directory.
+// All settings that can't be set, including files that can't be read,
+// are reported as congo.Errors. Entries of the directory that can't be
+// read are only reported if they would hold the value of a setting.
+func (s *source) Load(settings map[string]*congo.Setting) error {
+	files, err := s.scan()
+	if err != nil {
+		return fmt.Errorf("dir-source: couldn't read the directory because: %s", err)
+	}
+	var errs congo.Errors
+	for _, key := range congo.Names(settings) {
+		if _, ok := settings[key].Value.(congo.GroupValue); ok {
+			continue
+		}
+		for _, alternative := range s.translator(key) {
+			file, ok := s.lookup(files, alternative)
+			if !ok {
+				continue
+			}
+			if file.err != nil {
+				errs.Append(&congo.LoadError{Setting: key, Source: sourceName, Location: file.path, Err: file.err})
+				break
+			}
+			content, err := ioutil.ReadFile(file.path)
+			if err != nil {
+				errs.Append(&congo.LoadError{Setting: key, Source: sourceName, Location: file.path, Err: err})
+				break
+			}
+			origin := congo.Origin{Source: sourceName, Location: file.path}
+			errs.Append(settings[key].Set(strings.TrimSpace(string(content)), origin))
+			break
+		}
+	}
+	return errs.Err()
+}
+
+// entry is an entry of the directory.
+type entry struct {
+	path string
+	err  error // why the entry couldn't be read; nil for readable files
+}
+
+// lookup returns the entry of the file with given name. If the file is
+// part of a sub-directory that couldn't be read, the sub-directory is returned.
+func (s *source) lookup(files map[string]entry, name string) (entry, bool) {
+	if file, ok := files[name]; ok || !s.recursive {
+		return file, ok
+	}
+	for i := strings.LastIndex(name, congo.NameSeparator); i >= 0; i = strings.LastIndex(name, congo.NameSeparator) {
+		name = name[:i]
+		if dir, ok := files[name]; ok && dir.err != nil {
+			return dir, true
+		}
+	}
+	return entry{}, false
+}
+
+// scan returns the entries of all files in the directory by their names.
+// Only failing to read the directory itself is returned as error, entries
+// that can't be read are returned with the reason.
+func (s *source) scan() (map[string]entry, error) {
+	files := make(map[string]entry)
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) && s.looseLoad {
+			return files, nil
+		}
+		return nil, err
+	}
+	return files, s.scanDir(s.path, "", []os.FileInfo{info}, files)
+}
+
+// scanDir adds the files in given directory to files. The names of the files
+// are prefixed with given prefix. Parents are the directories containing the
+// directory, so symbolic links pointing to them aren't followed endlessly.
+// Entries that vanished (e.g. dangling symbolic links) are skipped; all other
+// entries that can't be read are added with the reason and the scan continues.
+func (s *source) scanDir(dir string, prefix string, parents []os.FileInfo, files map[string]entry) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.HasPrefix(name, "..") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if prefix != "" {
+			name = prefix + congo.NameSeparator + name
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				files[name] = entry{path, err}
+			}
+			continue
+		}
+		if !info.IsDir() {
+			files[name] = entry{path, nil}
+			continue
+		}
+		if !s.recursive || isParent(info, parents) {
+			continue
+		}
+		if err := s.scanDir(path, name, append(parents[:len(parents):len(parents)], info), files); err != nil {
+			files[name] = entry{path, err}
+		}
+	}
+	return nil
+}
+
+// isParent returns whether the directory is one of given parents.
+func isParent(dir os.FileInfo, parents []os.FileInfo) bool {
+	for _, parent := range parents {
+		if os.SameFile(dir, parent) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package dir
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/env"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// createDir creates a temporary directory containing given files by their
+// paths relative to the directory. It returns the path of the directory.
+func createDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "congo")
+	if err != nil {
+		t.Fatalf("Couldn't create temporary directory: %s", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Couldn't create directory for %s: %s", name, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Couldn't create file %s: %s", name, err)
+		}
+	}
+	return dir
+}
+
+func TestSource_Load(t *testing.T) {
+	dir := createDir(t, map[string]string{
+		"name":              "  congo\n",
+		"port":              "8080\n",
+		"database/password": "secret\n",
+	})
+	defer os.RemoveAll(dir)
+
+	cfg := congo.New("test", New(dir))
+	name := cfg.String("name", "", "")
+	port := cfg.Int("port", 0, "")
+	password := cfg.String("database.password", "", "")
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *name != "congo" || *port != 8080 {
+		t.Errorf("Expected name congo and port 8080.\nBut got: %q and %d\n", *name, *port)
+	}
+	if *password != "" {
+		t.Errorf("Expected subdirectories to be ignored.\nBut got password: %q\n", *password)
+	}
+	origin := congo.Origin{Source: "dir", Location: filepath.Join(dir, "name"), Raw: "congo"}
+	if got, _ := cfg.Origin("name"); got != origin {
+		t.Errorf("Expected origin %+v.\nBut got: %+v\n", origin, got)
+	}
+}
+
+func TestSource_SetRecursive(t *testing.T) {
+	dir := createDir(t, map[string]string{
+		"database/password":  "secret",
+		"database/pool/size": "10",
+	})
+	defer os.RemoveAll(dir)
+
+	src := New(dir).SetRecursive(true)
+	cfg := congo.New("test", src)
+	password := cfg.String("database.password", "", "")
+	size := cfg.Int("database.pool.size", 0, "")
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *password != "secret" || *size != 10 {
+		t.Errorf("Expected password secret and size 10.\nBut got: %q and %d\n", *password, *size)
+	}
+	expected := []string{filepath.Join(dir, "database", "pool", "size")}
+	if names := src.(congo.Namer).Names("database.pool.size"); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected names %v.\nBut got: %v\n", expected, names)
+	}
+}
+
+// TestSource_Kubernetes tests a directory laid out like a mounted ConfigMap:
+// the files are symbolic links into the ..data directory, which itself links
+// to a timestamped directory.
+func TestSource_Kubernetes(t *testing.T) {
+	dir := createDir(t, map[string]string{
+		"..2018_01_01_00_00_00.000000000/level": "debug",
+	})
+	defer os.RemoveAll(dir)
+	if err := os.Symlink("..2018_01_01_00_00_00.000000000", filepath.Join(dir, "..data")); err != nil {
+		t.Skipf("Symbolic links aren't supported: %s", err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "level"), filepath.Join(dir, "level")); err != nil {
+		t.Fatalf("Couldn't create symbolic link: %s", err)
+	}
+	// Link back to the directory itself to make sure it isn't followed endlessly.
+	if err := os.Symlink(".", filepath.Join(dir, "self")); err != nil {
+		t.Fatalf("Couldn't create symbolic link: %s", err)
+	}
+
+	src := New(dir).SetRecursive(true)
+	files, err := src.(*source).scan()
+	if err != nil {
+		t.Fatalf("Expected to scan without problems.\nBut got error: %s\n", err)
+	}
+	expected := map[string]entry{"level": {filepath.Join(dir, "level"), nil}}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected files %v.\nBut got: %v\n", expected, files)
+	}
+
+	cfg := congo.New("test", src)
+	level := cfg.String("level", "info", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *level != "debug" {
+		t.Errorf("Expected level debug.\nBut got: %q\n", *level)
+	}
+}
+
+func TestSource_WithTranslator(t *testing.T) {
+	dir := createDir(t, map[string]string{
+		"APP_DB_PASSWORD": "secret",
+	})
+	defer os.RemoveAll(dir)
+
+	cfg := congo.New("test", New(dir).WithTranslator(env.PrefixSdtTranslator("app.")))
+	password := cfg.String("db.password", "", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *password != "secret" {
+		t.Errorf("Expected password secret.\nBut got: %q\n", *password)
+	}
+}
+
+func TestSource_LoadErrors(t *testing.T) {
+	dir := createDir(t, map[string]string{
+		"port": "eighty",
+	})
+	defer os.RemoveAll(dir)
+
+	cfg := congo.New("test", New(dir))
+	cfg.Int("port", 0, "")
+	cfg.Init()
+	err := cfg.Load()
+	var loadErr *congo.LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("Expected a *congo.LoadError.\nBut got: %v\n", err)
+	}
+	if path := filepath.Join(dir, "port"); loadErr.Location != path || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected the error to carry the path %s.\nBut got: %s\n", path, err)
+	}
+}
+
+func TestSource_LoadBrokenEntries(t *testing.T) {
+	dir := createDir(t, map[string]string{
+		"port": "80",
+	})
+	defer os.RemoveAll(dir)
+	// Symbolic links to themselves can't be read.
+	for _, name := range []string{"loop", "unrelated", "db"} {
+		if err := os.Symlink(name, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Couldn't create symbolic link: %s", err)
+		}
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatalf("Couldn't create symbolic link: %s", err)
+	}
+
+	cfg := congo.New("test", New(dir).SetRecursive(true))
+	port := cfg.Int("port", 0, "")
+	cfg.String("loop", "", "")
+	cfg.String("dangling", "", "")
+	cfg.String("db.password", "", "")
+	cfg.Init()
+	err := cfg.Load()
+	errs, ok := err.(congo.Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected errors for loop and db.password only.\nBut got: %v\n", err)
+	}
+	for i, expected := range []struct{ setting, path string }{
+		{"db.password", filepath.Join(dir, "db")},
+		{"loop", filepath.Join(dir, "loop")},
+	} {
+		var loadErr *congo.LoadError
+		if !errors.As(errs[i], &loadErr) || loadErr.Setting != expected.setting || loadErr.Location != expected.path {
+			t.Errorf("Expected a *congo.LoadError for %s at %s.\nBut got: %v\n", expected.setting, expected.path, errs[i])
+		}
+	}
+	if *port != 80 {
+		t.Errorf("Expected port 80 to be loaded despite the broken entries.\nBut got: %d\n", *port)
+	}
+}
+
+func TestSource_SetLooseLoad(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "congo-missing-directory")
+	cfg := congo.New("test", New(dir))
+	cfg.String("name", "", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected missing directory to be ignored.\nBut got error: %s\n", err)
+	}
+
+	cfg = congo.New("test", New(dir).SetLooseLoad(false))
+	cfg.String("name", "", "")
+	cfg.Init()
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), dir) {
+		t.Errorf("Expected an error naming %s.\nBut got: %v\n", dir, err)
+	}
+}
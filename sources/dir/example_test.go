@@ -0,0 +1,41 @@
+package dir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Example is a basic example for the usage of the directory source.
+func Example() {
+	// Set up a directory like /run/secrets
+	secrets, _ := ioutil.TempDir("", "secrets")
+	defer os.RemoveAll(secrets)
+	ioutil.WriteFile(filepath.Join(secrets, "password"), []byte("s3cr3t\n"), 0600)
+
+	// Configuration
+	cfg := congo.New("main", New(secrets))
+
+	user := cfg.String("user", "admin", "The user to log in with")
+	password := cfg.String("password", "", "The password of the user")
+
+	// Load configurations
+	cfg.Init()
+	cfg.Load()
+
+	fmt.Printf("Logging in as %s with password %s\n", *user, *password)
+
+	//Output:
+	//Logging in as admin with password s3cr3t
+}
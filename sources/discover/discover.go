@@ -0,0 +1,182 @@
+// Package discover provides a source searching the configuration files of
+// an application in the standard locations: the working directory, the user
+// and system directories of the XDG Base Directory Specification and /etc.
+package discover
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// sourceName is the name used in errors of this source.
+const sourceName = "discover"
+
+// Opener creates the source loading the configuration file at given path
+// e.g. func(path string) congo.Source { return ini.FromFile(path) }.
+type Opener func(path string) congo.Source
+
+// New creates a new source which loads the configuration files named after
+// the configuration with given extension (e.g. ".ini") that exist in the
+// paths returned by SearchPaths. The files are opened using given opener.
+//
+// All files found are loaded. Files found earlier in the search paths are
+// preferred, so the user's configuration overrides the system's.
+func New(ext string, open Opener) Source {
+	return &source{ext: ext, open: open, sources: make(map[string]congo.Source)}
+}
+
+// SearchPaths returns the paths of the configuration files for the
+// application with given name in order of preference:
+//
+//	./<name><ext>
+//	$XDG_CONFIG_HOME/<name>/<name><ext> (default ~/.config)
+//	$XDG_CONFIG_DIRS/<name>/<name><ext> for each directory (default /etc/xdg)
+//	/etc/<name>/<name><ext>
+func SearchPaths(name, ext string) []string {
+	file := name + ext
+	paths := []string{file}
+	if home := os.Getenv("XDG_CONFIG_HOME"); home != "" {
+		paths = append(paths, filepath.Join(home, name, file))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", name, file))
+	}
+	dirs := filepath.SplitList(os.Getenv("XDG_CONFIG_DIRS"))
+	if len(dirs) == 0 {
+		dirs = []string{"/etc/xdg"}
+	}
+	for _, dir := range dirs {
+		if dir != "" {
+			paths = append(paths, filepath.Join(dir, name, file))
+		}
+	}
+	return append(paths, filepath.Join("/etc", name, file))
+}
+
+// Source is a source that loads the configuration files it finds
+// in the standard search paths.
+type Source interface {
+	congo.Source
+	// SetFirst sets whether only the first file found is loaded
+	// instead of all of them. Default is false.
+	SetFirst(first bool) Source
+	// Files returns the paths of the files loaded by the last Load()
+	// in order of preference.
+	Files() []string
+}
+
+type source struct {
+	ext      string
+	open     Opener
+	first    bool
+	name     string
+	congo    congo.Congo
+	settings map[string]*congo.Setting
+	sources  map[string]congo.Source // sources of the files opened so far by path
+	files    []string                // files loaded by the last Load()
+}
+
+// SetFirst sets whether only the first file found is loaded
+// instead of all of them. Default is false.
+func (s *source) SetFirst(first bool) Source {
+	s.first = first
+	return s
+}
+
+// Files returns the paths of the files loaded by the last Load()
+// in order of preference.
+func (s *source) Files() []string {
+	return append([]string(nil), s.files...)
+}
+
+// Attach records the name of the configuration the files are named after.
+func (s *source) Attach(c congo.Congo) {
+	s.name = c.Name()
+	s.congo = c
+	for _, source := range s.sources {
+		if attacher, ok := source.(congo.Attacher); ok {
+			attacher.Attach(c)
+		}
+	}
+}
+
+// Init initializes this source
+func (s *source) Init(settings map[string]*congo.Setting) error {
+	s.settings = settings
+	return nil
+}
+
+// Load searches the configuration files and loads the settings from them.
+// The errors of all files are reported as congo.Errors.
+func (s *source) Load(settings map[string]*congo.Setting) error {
+	if s.name == "" {
+		return fmt.Errorf("%s-source: can't search configuration files of a configuration without name", sourceName)
+	}
+	var errs congo.Errors
+	s.files = nil
+	for _, path := range SearchPaths(s.name, s.ext) {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil && info.IsDir() {
+			err = fmt.Errorf("%s is a directory", path)
+		}
+		if err != nil {
+			errs.Append(fmt.Errorf("%s-source: couldn't use configuration file because: %s", sourceName, err))
+			continue
+		}
+		s.files = append(s.files, path)
+		if s.first {
+			break
+		}
+	}
+	// Files preferred are loaded last, so they override the others.
+	for i := len(s.files) - 1; i >= 0; i-- {
+		source, err := s.source(s.files[i])
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+		errs.Append(source.Load(settings))
+	}
+	return errs.Err()
+}
+
+// source returns the source of the file at given path. Sources are opened,
+// attached and initialized the first time they are used.
+func (s *source) source(path string) (congo.Source, error) {
+	if source, ok := s.sources[path]; ok {
+		return source, nil
+	}
+	source := s.open(path)
+	if attacher, ok := source.(congo.Attacher); ok && s.congo != nil {
+		attacher.Attach(s.congo)
+	}
+	if err := source.Init(s.settings); err != nil {
+		return nil, err
+	}
+	s.sources[path] = source
+	return source, nil
+}
+
+// Names returns the names the loaded files know the setting by.
+func (s *source) Names(setting string) []string {
+	var names []string
+	for _, path := range s.files {
+		if namer, ok := s.sources[path].(congo.Namer); ok {
+			names = append(names, namer.Names(setting)...)
+		}
+	}
+	return names
+}
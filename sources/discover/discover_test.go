@@ -0,0 +1,142 @@
+package discover
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/ini"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// name is the name of the configuration used in the tests.
+// It must not exist in /etc.
+const name = "congo-discover-test"
+
+func openIni(path string) congo.Source {
+	return ini.FromFile(path)
+}
+
+// setup creates a working directory and XDG directories containing
+// given files by their paths relative to the root of the directories.
+// It returns the root and a function restoring the environment.
+func setup(t *testing.T, files map[string]string) (string, func()) {
+	root, err := ioutil.TempDir("", "congo")
+	if err != nil {
+		t.Fatalf("Couldn't create temporary directory: %s", err)
+	}
+	for _, dir := range []string{"work", "home", "dirs1", "dirs2"} {
+		os.Mkdir(filepath.Join(root, dir), 0755)
+	}
+	for path, content := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Couldn't create directory for %s: %s", path, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Couldn't create file %s: %s", path, err)
+		}
+	}
+	wd, _ := os.Getwd()
+	home, dirs := os.Getenv("XDG_CONFIG_HOME"), os.Getenv("XDG_CONFIG_DIRS")
+	os.Chdir(filepath.Join(root, "work"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "home"))
+	os.Setenv("XDG_CONFIG_DIRS", filepath.Join(root, "dirs1")+string(filepath.ListSeparator)+filepath.Join(root, "dirs2"))
+	return root, func() {
+		os.Chdir(wd)
+		os.Setenv("XDG_CONFIG_HOME", home)
+		os.Setenv("XDG_CONFIG_DIRS", dirs)
+		os.RemoveAll(root)
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+	root, teardown := setup(t, nil)
+	defer teardown()
+	expected := []string{
+		"app.ini",
+		filepath.Join(root, "home", "app", "app.ini"),
+		filepath.Join(root, "dirs1", "app", "app.ini"),
+		filepath.Join(root, "dirs2", "app", "app.ini"),
+		filepath.Join("/etc", "app", "app.ini"),
+	}
+	if paths := SearchPaths("app", ".ini"); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected paths %v.\nBut got: %v\n", expected, paths)
+	}
+}
+
+func TestSource_Load(t *testing.T) {
+	root, teardown := setup(t, map[string]string{
+		"work/" + name + ".ini":               "a = work",
+		"home/" + name + "/" + name + ".ini":  "a = home\nb = home",
+		"dirs2/" + name + "/" + name + ".ini": "a = system\nb = system\nc = system",
+	})
+	defer teardown()
+
+	src := New(".ini", openIni)
+	cfg := congo.New(name, src)
+	a := cfg.String("a", "", "")
+	b := cfg.String("b", "", "")
+	c := cfg.String("c", "", "")
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *a != "work" || *b != "home" || *c != "system" {
+		t.Errorf("Expected work, home and system.\nBut got: %s, %s and %s\n", *a, *b, *c)
+	}
+	expected := []string{
+		name + ".ini",
+		filepath.Join(root, "home", name, name+".ini"),
+		filepath.Join(root, "dirs2", name, name+".ini"),
+	}
+	if files := src.Files(); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected files %v.\nBut got: %v\n", expected, files)
+	}
+	if origin, _ := cfg.Origin("b"); !strings.HasPrefix(origin.Location, expected[1]) {
+		t.Errorf("Expected b to originate from %s.\nBut got: %+v\n", expected[1], origin)
+	}
+
+	src.SetFirst(true)
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Expected to reload without problems.\nBut got error: %s\n", err)
+	}
+	if *a != "work" || *b != "" || *c != "" {
+		t.Errorf("Expected only the first file to be loaded.\nBut got: %s, %s and %s\n", *a, *b, *c)
+	}
+	if files := src.Files(); !reflect.DeepEqual(files, expected[:1]) {
+		t.Errorf("Expected files %v.\nBut got: %v\n", expected[:1], files)
+	}
+}
+
+func TestSource_LoadErrors(t *testing.T) {
+	_, teardown := setup(t, map[string]string{
+		"work/" + name + ".ini/file": "",
+	})
+	defer teardown()
+
+	cfg := congo.New(name, New(".ini", openIni))
+	cfg.Init()
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), name+".ini is a directory") {
+		t.Errorf("Expected an error about the directory.\nBut got: %v\n", err)
+	}
+
+	cfg = congo.New("", New(".ini", openIni))
+	cfg.Init()
+	if err := cfg.Load(); err == nil {
+		t.Errorf("Expected an error for a configuration without name.\n")
+	}
+}
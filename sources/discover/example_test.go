@@ -0,0 +1,38 @@
+package discover
+
+import (
+	"fmt"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/ini"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Example is a basic example for the usage of the discovery source.
+func Example() {
+	// Search e.g. ./myapp.ini, ~/.config/myapp/myapp.ini and /etc/myapp/myapp.ini
+	files := New(".ini", func(path string) congo.Source {
+		return ini.FromFile(path)
+	})
+
+	// Configuration
+	cfg := congo.New("myapp", files)
+
+	port := cfg.Int("port", 8080, "The port to listen on")
+
+	// Load configurations
+	cfg.Init()
+	cfg.Load()
+
+	fmt.Printf("Listening on port %d using %d configuration files\n", *port, len(files.Files()))
+
+	//Output:
+	//Listening on port 8080 using 0 configuration files
+}
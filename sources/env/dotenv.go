@@ -0,0 +1,190 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gitlab.com/silentteacup/congo/internal/input"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// dotenvSourceName is the name used for the origin of settings set from a .env file.
+const dotenvSourceName = "dotenv"
+
+// FromFile creates a new environment source which loads settings from
+// the variables defined in the .env file at given path instead of the
+// environment of the process. Translators apply just like for New(), so
+// the file can use the names of the real environment variables.
+//
+// The file contains a KEY=value definition per line. Lines may start with
+// "export " and comments start with '#'. Values can be quoted: single quoted
+// values are taken literally, double quoted values may contain the escapes
+// \n, \r, \t, \", \\ and \$. Both may span several lines. References to
+// variables like ${VAR} or $VAR are expanded in unquoted and double quoted
+// values using the variables defined before and the environment of the process.
+func FromFile(path string) Source {
+	return &source{translator: IdenticalTranslator, file: input.New(path), looseLoad: true}
+}
+
+// FromBytes creates a new environment source which loads settings from
+// the variables defined by given content of a .env file (see FromFile).
+func FromBytes(content []byte) Source {
+	return &source{translator: IdenticalTranslator, file: input.New(content), looseLoad: true}
+}
+
+var dotenvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// parseDotenv parses the variables defined in data.
+func parseDotenv(name string, data []byte) (map[string]variable, error) {
+	variables := make(map[string]variable)
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		location := fmt.Sprintf("%s:%d", name, i+1)
+		line := strings.TrimLeft(lines[i], " \t")
+		if strings.TrimSpace(line) == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimLeft(line[len("export "):], " \t")
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("%s: expected KEY=value", location)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !dotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("%s: invalid variable name %q", location, key)
+		}
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+		var value string
+		escapes := false
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote, text := rest[0], rest[1:]
+			end := closingQuote(text, quote)
+			// Quoted values may span several lines.
+			for ; end < 0 && i+1 < len(lines); end = closingQuote(text, quote) {
+				i++
+				text += "\n" + lines[i]
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("%s: unterminated quoted value", location)
+			}
+			if after := strings.TrimSpace(text[end+1:]); after != "" && after[0] != '#' {
+				return nil, fmt.Errorf("%s: unexpected content after quoted value", location)
+			}
+			value, escapes = text[:end], quote == '"'
+			if quote == '\'' {
+				variables[key] = variable{value, location}
+				continue
+			}
+		} else {
+			value = strings.TrimSpace(stripComment(rest))
+		}
+		value, err := expand(value, escapes, variables)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", location, err)
+		}
+		variables[key] = variable{value, location}
+	}
+	return variables, nil
+}
+
+// stripComment removes a comment from an unquoted value. Comments start
+// with '#' at the beginning of the value or after whitespace.
+func stripComment(value string) string {
+	for i := 0; i < len(value); i++ {
+		if value[i] == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i]
+		}
+	}
+	return value
+}
+
+// closingQuote returns the index of the quote closing a quoted value in
+// text or -1 if the value isn't closed. Double quotes can be escaped.
+func closingQuote(text string, quote byte) int {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// expand expands references to variables in value. Variables defined before
+// are preferred over variables of the process environment; undefined variables
+// are empty. If escapes is set backslash escapes are replaced as well.
+func expand(value string, escapes bool, variables map[string]variable) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case escapes && c == '\\' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(value[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(value[i])
+			}
+		case c == '$' && i+1 < len(value) && value[i+1] == '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference in %q", value)
+			}
+			b.WriteString(lookup(value[i+2:i+end], variables))
+			i += end
+		case c == '$' && i+1 < len(value) && isNameStart(value[i+1]):
+			end := i + 2
+			for end < len(value) && (isNameStart(value[end]) || '0' <= value[end] && value[end] <= '9') {
+				end++
+			}
+			b.WriteString(lookup(value[i+1:end], variables))
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+// lookup returns the value of the variable with given name.
+func lookup(name string, variables map[string]variable) string {
+	if v, ok := variables[name]; ok {
+		return v.value
+	}
+	return os.Getenv(name)
+}
+
+// quoteDotenv returns value in .env syntax. Values are double quoted if
+// they wouldn't be read unchanged otherwise.
+func quoteDotenv(value string) string {
+	if value == strings.TrimSpace(value) && !strings.ContainsAny(value, "#\"'$\\\n\r") {
+		return value
+	}
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$", "\n", "\\n", "\r", "\\r")
+	return "\"" + r.Replace(value) + "\""
+}
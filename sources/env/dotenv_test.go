@@ -0,0 +1,133 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+const dotenv = `# Comment
+APP_NUMBER=54
+export APP_HOST = example.com # comment
+APP_URL=http://${APP_HOST}/#anchor
+APP_SINGLE='$APP_HOST \n'
+APP_DOUBLE="tab\tquote\" dollar\$ $APP_HOST"
+APP_MULTI="line 1
+line 2"
+APP_HOME=${CONGO_DOTENV_HOME}
+APP_EMPTY=
+`
+
+func TestFromBytes(t *testing.T) {
+	os.Setenv("CONGO_DOTENV_HOME", "/home/congo")
+	defer os.Unsetenv("CONGO_DOTENV_HOME")
+	cfg := congo.New("test", FromBytes([]byte(dotenv)).WithTranslator(PrefixSdtTranslator("app_")))
+	number := cfg.Int("number", 0, "")
+	host := cfg.String("host", "", "")
+	url := cfg.String("url", "", "")
+	single := cfg.String("single", "", "")
+	double := cfg.String("double", "", "")
+	multi := cfg.String("multi", "", "")
+	home := cfg.String("home", "", "")
+	empty := cfg.String("empty", "default", "")
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *number != 54 || *host != "example.com" || *url != "http://example.com/#anchor" {
+		t.Errorf("Expected unquoted values to be loaded.\nBut got: %v %q %q\n", *number, *host, *url)
+	}
+	if *single != `$APP_HOST \n` || *double != "tab\tquote\" dollar$ example.com" || *multi != "line 1\nline 2" {
+		t.Errorf("Expected quoted values to be loaded.\nBut got: %q %q %q\n", *single, *double, *multi)
+	}
+	if *home != "/home/congo" || *empty != "" {
+		t.Errorf("Expected process environment to be expanded.\nBut got: %q %q\n", *home, *empty)
+	}
+	expected := congo.Origin{Source: "dotenv", Raw: "54", Location: "<bytes>:2"}
+	if origin, _ := cfg.Origin("number"); origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+}
+
+func TestFromBytes_Invalid(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"A=1\nB\n", "<bytes>:2: expected KEY=value"},
+		{"1A=1\n", "<bytes>:1: invalid variable name"},
+		{"A=\"open\nB=2\n", "<bytes>:1: unterminated quoted value"},
+		{"A='a' b\n", "<bytes>:1: unexpected content"},
+		{"A=${B\n", "<bytes>:1: unterminated variable reference"},
+	}
+	for _, test := range tests {
+		err := FromBytes([]byte(test.content)).Load(map[string]*congo.Setting{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q for %q.\nBut got: %v\n", test.expected, test.content, err)
+		}
+	}
+}
+
+func TestFromFile_NotLoose(t *testing.T) {
+	settings := map[string]*congo.Setting{}
+	if err := FromFile("").Load(settings); err != nil {
+		t.Errorf("Expected load with non-existent file to work without errors.\nBut got error: %s\n", err)
+	}
+	if err := FromFile("").SetLooseLoad(false).Load(settings); err == nil {
+		t.Errorf("Expected load with non-existent file to fail.\n")
+	}
+}
+
+func TestFromBytes_Strict(t *testing.T) {
+	src := FromBytes([]byte("APP_NUMBER=1\nAPP_NUMBR=2\n")).
+		WithTranslator(PrefixSdtTranslator("app_")).WithStrictPrefix("APP_")
+	cfg := congo.New("test", src)
+	cfg.Int("number", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var unknown *congo.UnknownError
+	if !errors.As(err, &unknown) || unknown.Location != "<bytes>:2" || unknown.Suggestion != "APP_NUMBER" {
+		t.Errorf("Expected unknown variable to be reported.\nBut got: %v\n", err)
+	}
+}
+
+func TestSource_WriteExample(t *testing.T) {
+	src := New().WithTranslator(PrefixSdtTranslator("app_"))
+	cfg := congo.New("test", src)
+	cfg.Int("max-users", 60, "Maximum number of users", congo.Min("1"))
+	cfg.String("greeting", "hello $USER # hi", "")
+	cfg.String("database.host", "localhost", "")
+	cfg.Init()
+
+	var buffer bytes.Buffer
+	if err := src.WriteExample(&buffer); err != nil {
+		t.Fatalf("Expected to write without problems.\nBut got error: %s\n", err)
+	}
+	expected := "APP_DATABASE_HOST=localhost\n" +
+		"APP_GREETING=\"hello \\$USER # hi\"\n" +
+		"# Maximum number of users (min=1)\n" +
+		"APP_MAX_USERS=60\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected example:\n%s\nBut got:\n%s\n", expected, buffer.String())
+	}
+
+	// The example is read unchanged.
+	variables, err := parseDotenv("example", buffer.Bytes())
+	if err != nil || variables["APP_GREETING"].value != "hello $USER # hi" {
+		t.Errorf("Expected example to be read unchanged.\nBut got: %v (error: %v)\n", variables, err)
+	}
+}
@@ -1,15 +1,19 @@
 package env
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 
 	"strings"
 
 	"regexp"
 
-	"fmt"
+	"sort"
 
 	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/internal/input"
 )
 
 /*
@@ -20,10 +24,13 @@ License, v. 2.0. If a copy of the MPL was not distributed with this
 file, You can obtain one at http://mozilla.org/MPL/2.0/.
 */
 
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "env"
+
 // New creates a new environment source. Which directly
 // loads settings from environment variables.
 func New() Source {
-	return &source{IdenticalTranslator}
+	return &source{translator: IdenticalTranslator, looseLoad: true}
 }
 
 // Translator is used to translate the settings names to more conventional
@@ -40,13 +47,15 @@ var sdtTranslatorFilter = regexp.MustCompile("[^A-Z0-9_]")
 
 // PrefixSdtTranslator returns a translator that translates the key string
 // into a environment variable form. The given prefix will be added in front
-// of the key and after that spaces and hyphens will be converted to '_' and the string
-// will be converted to uppercase. Finally all characters except A-Z and 0-9 or _
+// of the key and after that spaces, hyphens and name separators (e.g. of
+// "database.pool.max-size") will be converted to '_' and the string will be
+// converted to uppercase. Finally all characters except A-Z and 0-9 or _
 // will be removed.
 func PrefixSdtTranslator(prefix string) Translator {
 	return func(s string) []string {
 		s = strings.ToUpper(strings.Replace(prefix+s, " ", "_", -1))
 		s = strings.Replace(s, "-", "_", -1)
+		s = strings.Replace(s, congo.NameSeparator, "_", -1)
 		return []string{sdtTranslatorFilter.ReplaceAllString(s, "")}
 	}
 }
@@ -62,10 +71,30 @@ type Source interface {
 	// The alternative representations are ordered representations given first will
 	// be preferred over others.
 	WithTranslator(t Translator) Source
+	// WithStrictPrefix makes the source report environment variables starting
+	// with given prefix that don't belong to any setting (e.g. misspelled
+	// variables) as *congo.UnknownError.
+	WithStrictPrefix(prefix string) Source
+	// SetLooseLoad sets whether a source reading a .env file should complain
+	// if the file doesn't exist. Default is true.
+	SetLooseLoad(loose bool) Source
+	// WriteExample writes an example of all variables that set the settings
+	// in .env syntax (e.g. for a .env.example file).
+	WriteExample(w io.Writer) error
 }
 
 type source struct {
-	translator Translator
+	translator   Translator
+	strictPrefix string       // prefix of variables checked in strict mode; empty if not strict
+	file         *input.Input // .env file the variables are read from; nil for the process environment
+	looseLoad    bool
+	defaults     map[string]*congo.Setting
+}
+
+// variable is the value of an environment variable and where it is defined.
+type variable struct {
+	value    string
+	location string
 }
 
 // WithTranslator add a translator function that translates a
@@ -80,25 +109,157 @@ func (s *source) WithTranslator(t Translator) Source {
 	return s
 }
 
+// WithStrictPrefix makes the source report environment variables starting
+// with given prefix that don't belong to any setting (e.g. misspelled
+// variables) as *congo.UnknownError.
+func (s *source) WithStrictPrefix(prefix string) Source {
+	s.strictPrefix = prefix
+	return s
+}
+
+// Names returns the environment variables that set the setting with given name.
+// Groups (see congo.GroupValue) aren't set by any variable.
+func (s *source) Names(setting string) []string {
+	if isGroup(s.defaults[setting]) {
+		return nil
+	}
+	return s.translator(setting)
+}
+
+// isGroup returns whether given setting is a group (see congo.GroupValue).
+// Groups can't be set by environment variables.
+func isGroup(setting *congo.Setting) bool {
+	if setting == nil {
+		return false
+	}
+	_, ok := setting.Value.(congo.GroupValue)
+	return ok
+}
+
+// SetLooseLoad sets whether a source reading a .env file should complain
+// if the file doesn't exist. Default is true.
+func (s *source) SetLooseLoad(loose bool) Source {
+	s.looseLoad = loose
+	return s
+}
+
 // Inits initializes this source
-func (s *source) Init(map[string]*congo.Setting) error {
-	// Do nothing
+func (s *source) Init(settings map[string]*congo.Setting) error {
+	s.defaults = settings
 	return nil
 }
 
+// name returns the name used for the origin of settings set by this source.
+func (s *source) name() string {
+	if s.file != nil {
+		return dotenvSourceName
+	}
+	return sourceName
+}
+
+// environment returns the variables settings are loaded from.
+func (s *source) environment() (map[string]variable, error) {
+	if s.file != nil {
+		data, err := s.file.Content(s.looseLoad)
+		if err != nil {
+			return nil, err
+		}
+		return parseDotenv(s.file.Name(), data)
+	}
+	variables := make(map[string]variable)
+	for _, v := range os.Environ() {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 {
+			variables[parts[0]] = variable{parts[1], parts[0]}
+		}
+	}
+	return variables, nil
+}
+
 // Load loads settings from environment variables.
+// All settings that can't be set are reported as congo.Errors.
 func (s *source) Load(settings map[string]*congo.Setting) error {
-	for key, setting := range settings {
+	variables, err := s.environment()
+	if err != nil {
+		return fmt.Errorf("%s-source: couldn't load the env-file because: %s", s.name(), err)
+	}
+	var errs congo.Errors
+	for _, key := range congo.Names(settings) {
+		if isGroup(settings[key]) {
+			continue
+		}
 		for _, alternative := range s.translator(key) {
-			value, ok := os.LookupEnv(alternative)
+			v, ok := variables[alternative]
 			if ok {
-				if err := setting.Value.Set(value); err != nil {
-					return fmt.Errorf("env-source: couldn't read setting %q: "+
-						"%s", key, err)
-				}
+				origin := congo.Origin{Source: s.name(), Location: v.location}
+				errs.Append(settings[key].Set(v.value, origin))
 				break
 			}
 		}
 	}
-	return nil
+	if s.strictPrefix != "" {
+		errs.Append(s.checkUnknown(settings, variables))
+	}
+	return errs.Err()
+}
+
+// checkUnknown reports all variables starting with the strict prefix that
+// don't belong to any of given settings as *congo.UnknownError.
+func (s *source) checkUnknown(settings map[string]*congo.Setting, variables map[string]variable) error {
+	var known []string
+	isKnown := make(map[string]bool)
+	for _, key := range congo.Names(settings) {
+		if isGroup(settings[key]) {
+			continue
+		}
+		for _, alternative := range s.translator(key) {
+			known = append(known, alternative)
+			isKnown[alternative] = true
+		}
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs congo.Errors
+	for _, name := range names {
+		if !strings.HasPrefix(name, s.strictPrefix) || isKnown[name] {
+			continue
+		}
+		unknown := &congo.UnknownError{
+			Source:     s.name(),
+			Key:        name,
+			Suggestion: congo.Suggest(name, known),
+		}
+		if s.file != nil {
+			unknown.Location = variables[name].location
+		}
+		errs.Append(unknown)
+	}
+	return errs.Err()
+}
+
+// WriteExample writes an example of all variables that set the settings
+// in .env syntax (e.g. for a .env.example file). Every setting is set to its
+// default value using the first variable the translator returns for it.
+// The help of a setting is written as comment above it.
+// If an error occurs nothing will be written.
+func (s *source) WriteExample(w io.Writer) error {
+	var buffer bytes.Buffer
+	for _, name := range congo.Names(s.defaults) {
+		setting := s.defaults[name]
+		alternatives := s.translator(name)
+		if len(alternatives) == 0 || isGroup(setting) {
+			continue
+		}
+		if help := setting.Help(); help != "" {
+			for _, line := range strings.Split(help, "\n") {
+				buffer.WriteString("# " + line + "\n")
+			}
+		}
+		buffer.WriteString(alternatives[0] + "=" + quoteDotenv(setting.DefValue) + "\n")
+	}
+	_, err := buffer.WriteTo(w)
+	return err
 }
@@ -1,6 +1,7 @@
 package env
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -46,10 +47,9 @@ func TestSource_WithTranslator(t *testing.T) {
 	v := &mockValue{}
 	settings := map[string]*congo.Setting{
 		"NUMBER": {
-			"NUMBER",
-			"",
-			v,
-			"0",
+			Name:     "NUMBER",
+			Value:    v,
+			DefValue: "0",
 		},
 	}
 	if err := src.Init(settings); err != nil {
@@ -71,6 +71,10 @@ func TestSource_WithTranslator(t *testing.T) {
 		t.Errorf("Expected Set() to be called with %s as parameter"+
 			".\nBut was called with %s.\n", "5", v.SetParam)
 	}
+	origin := congo.Origin{Source: "env", Raw: "5", Location: "NUMBER"}
+	if *settings["NUMBER"].Origin != origin {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", origin, *settings["NUMBER"].Origin)
+	}
 
 	os.Unsetenv("NUMBER")
 	v.Reset()
@@ -98,3 +102,76 @@ func TestPrefixSdtTranslator(t *testing.T) {
 		t.Errorf("Expected translation to be %q\nBut got: %q\n", expected, result[0])
 	}
 }
+
+func TestPrefixSdtTranslator_Nested(t *testing.T) {
+	translator := PrefixSdtTranslator("app_")
+
+	result := translator("database.pool.max-size")
+
+	expected := "APP_DATABASE_POOL_MAX_SIZE"
+
+	if result[0] != expected {
+		t.Errorf("Expected translation to be %q\nBut got: %q\n", expected, result[0])
+	}
+}
+
+func TestSource_Load_Errors(t *testing.T) {
+	os.Setenv("CONGO_TEST_A", "a")
+	os.Setenv("CONGO_TEST_B", "b")
+	defer os.Unsetenv("CONGO_TEST_A")
+	defer os.Unsetenv("CONGO_TEST_B")
+	cfg := congo.New("test", New().WithTranslator(PrefixSdtTranslator("congo_test_")))
+	cfg.Int("a", 0, "")
+	cfg.Int("b", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var errs congo.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected both settings to be reported.\nBut got: %v\n", err)
+	}
+	var loadErr *congo.LoadError
+	if !errors.As(errs[1], &loadErr) || loadErr.Setting != "b" || loadErr.Location != "CONGO_TEST_B" {
+		t.Errorf("Expected error to describe setting %q.\nBut got: %v\n", "b", errs[1])
+	}
+}
+
+func TestSource_Load_Groups(t *testing.T) {
+	os.Setenv("CONGO_GROUP_UPSTREAM", "q")
+	defer os.Unsetenv("CONGO_GROUP_UPSTREAM")
+	src := New().WithTranslator(PrefixSdtTranslator("congo_group_"))
+	cfg := congo.New("test", src).Using(&struct {
+		Upstreams []struct {
+			Host string `name:"host"`
+		} `name:"upstream"`
+	}{})
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected the group to be skipped.\nBut got error: %s\n", err)
+	}
+	if names := src.(congo.Namer).Names("upstream"); len(names) != 0 {
+		t.Errorf("Expected no variable to set the group.\nBut got: %v\n", names)
+	}
+}
+
+func TestSource_Load_Strict(t *testing.T) {
+	os.Setenv("CONGO_STRICT_MAX_USERS", "10")
+	os.Setenv("CONGO_STRICT_MAX_USER", "10")
+	defer os.Unsetenv("CONGO_STRICT_MAX_USERS")
+	defer os.Unsetenv("CONGO_STRICT_MAX_USER")
+	src := New().WithTranslator(PrefixSdtTranslator("congo_strict_")).WithStrictPrefix("CONGO_STRICT_")
+	cfg := congo.New("test", src)
+	cfg.Int("max-users", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var unknown *congo.UnknownError
+	if !errors.As(err, &unknown) || !errors.Is(err, congo.ErrUnknown) {
+		t.Fatalf("Expected unknown variable to be reported.\nBut got: %v\n", err)
+	}
+	if unknown.Key != "CONGO_STRICT_MAX_USER" || unknown.Suggestion != "CONGO_STRICT_MAX_USERS" {
+		t.Errorf("Expected %q to be suggested for %q.\nBut got: %+v\n",
+			"CONGO_STRICT_MAX_USERS", "CONGO_STRICT_MAX_USER", unknown)
+	}
+}
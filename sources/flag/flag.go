@@ -28,7 +28,7 @@ type ArgLoader func() []string
 // argument loader. The argument loader specifies how arguments are loaded
 // when the flags are parsed.
 func FromFlagSet(set *flag.FlagSet, loader ArgLoader) congo.Source {
-	return &source{set, loader}
+	return &source{set, loader, nil, nil, nil}
 }
 
 // standardLoader loads the commandline arguments
@@ -39,17 +39,96 @@ func standardLoader() []string {
 type source struct {
 	set *flag.FlagSet
 	ArgLoader
+	values []*value        // values of the registered flags
+	errs   congo.Errors    // errors that occurred while parsing
+	groups map[string]bool // names of the groups, which can't be set by flags
 }
 
-// Init registers the flags for this source
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "flag"
+
+// value is the flag.Value of a setting. It records the flag as origin
+// of the setting and makes repeated flags append to settings that hold
+// several elements instead of overwriting them.
+//
+// Values that can't be set are recorded by the source instead of
+// stopping the parsing, so all invalid flags can be reported at once.
+type value struct {
+	setting *congo.Setting
+	name    string  // name of the flag
+	set     bool    // whether the flag was already set during parsing
+	src     *source // source the flag belongs to
+}
+
+// Set sets the setting when the flag occurs the first time and appends
+// to it afterwards.
+func (v *value) Set(s string) error {
+	origin := congo.Origin{Source: sourceName, Location: "-" + v.name}
+	if v.set {
+		v.src.errs.Append(v.setting.Append(s, origin))
+		return nil
+	}
+	v.set = true
+	v.src.errs.Append(v.setting.Set(s, origin))
+	return nil
+}
+
+// String returns the string representation of the value.
+func (v *value) String() string {
+	if v == nil || v.setting == nil {
+		return ""
+	}
+	return v.setting.Value.String()
+}
+
+// IsBoolFlag reports whether the flag can be used without a value.
+func (v *value) IsBoolFlag() bool {
+	b, ok := v.setting.Value.(interface {
+		IsBoolFlag() bool
+	})
+	return ok && b.IsBoolFlag()
+}
+
+// Names returns the flag that sets the setting with given name.
+// Groups (see congo.GroupValue) aren't set by any flag.
+func (s *source) Names(setting string) []string {
+	if s.groups[setting] {
+		return nil
+	}
+	return []string{"-" + setting}
+}
+
+// Attach makes the flag set print the usage of the whole configuration
+// when the flags are used incorrectly or help is requested (-h).
+func (s *source) Attach(c congo.Congo) {
+	s.set.Usage = func() {
+		c.PrintUsage(s.set.Output())
+	}
+}
+
+// Init registers the flags for this source. Groups are skipped, since
+// their entries can't be given as flags.
 func (s *source) Init(settings map[string]*congo.Setting) error {
+	s.groups = make(map[string]bool)
 	for key, setting := range settings {
-		s.set.Var(setting.Value, key, setting.Usage)
+		if _, ok := setting.Value.(congo.GroupValue); ok {
+			s.groups[key] = true
+			continue
+		}
+		v := &value{setting, key, false, s}
+		s.values = append(s.values, v)
+		s.set.Var(v, key, setting.Help())
 	}
 	return nil
 }
 
 // Load parses the flags using arguments loaded by the argument loader.
+// Invalid values of all flags are reported as congo.Errors.
 func (s *source) Load(settings map[string]*congo.Setting) error {
-	return s.set.Parse(s.ArgLoader())
+	for _, v := range s.values {
+		v.set = false
+	}
+	s.errs = nil
+	s.errs.Append(s.set.Parse(s.ArgLoader()))
+	return s.errs.Err()
 }
@@ -0,0 +1,177 @@
+package flag
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/sources/mapsource"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// newTestFlagSet creates a flag set that doesn't print anything.
+func newTestFlagSet() *flag.FlagSet {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	return set
+}
+
+// TestSource_Load_Repeated tests that repeated flags append to slice settings.
+func TestSource_Load_Repeated(t *testing.T) {
+	args := []string{"-tag", "a", "-tag", "b,c"}
+	src := FromFlagSet(newTestFlagSet(), func() []string { return args })
+	cfg := congo.New("test", src)
+	tags := cfg.StringSlice("tag", []string{"default"}, "")
+
+	if err := cfg.Init(); err != nil {
+		t.Errorf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	for i := 0; i < 2; i++ {
+		// Loading again must not append to the values of the previous load.
+		if err := cfg.Load(); err != nil {
+			t.Errorf("Expected to load without problems.\nBut got error: %s\n", err)
+		}
+		if len(*tags) != 3 || (*tags)[0] != "a" || (*tags)[2] != "c" {
+			t.Errorf("Expected repeated flags to be appended.\nBut got: %v\n", *tags)
+		}
+	}
+}
+
+// TestSource_Load_Origin tests that flags are recorded as origin of a setting.
+func TestSource_Load_Origin(t *testing.T) {
+	args := []string{"-number", "5"}
+	src := FromFlagSet(newTestFlagSet(), func() []string { return args })
+	cfg := congo.New("test", src)
+	cfg.Int("number", 0, "")
+	cfg.Bool("debug", false, "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	expected := congo.Origin{Source: "flag", Raw: "5", Location: "-number"}
+	if origin, _ := cfg.Origin("number"); origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+	if origin, _ := cfg.Origin("debug"); origin.Source != congo.DefaultSource {
+		t.Errorf("Expected origin to be the default.\nBut got: %+v\n", origin)
+	}
+}
+
+// TestSource_Load_Errors tests that all invalid flags are reported.
+func TestSource_Load_Errors(t *testing.T) {
+	args := []string{"-a", "x", "-b", "y", "-c", "3"}
+	src := FromFlagSet(newTestFlagSet(), func() []string { return args })
+	cfg := congo.New("test", src)
+	cfg.Int("a", 0, "")
+	cfg.Int("b", 0, "")
+	c := cfg.Int("c", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var errs congo.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected both invalid flags to be reported.\nBut got: %v\n", err)
+	}
+	if *c != 3 {
+		t.Errorf("Expected parsing to continue after invalid flags.\n")
+	}
+}
+
+// TestSource_Load_Help tests that -h prints the usage of the whole configuration.
+func TestSource_Load_Help(t *testing.T) {
+	var output bytes.Buffer
+	set := newTestFlagSet()
+	set.SetOutput(&output)
+	src := FromFlagSet(set, func() []string { return []string{"-h"} })
+	cfg := congo.New("test", src)
+	cfg.Int("max-users", 60, "Maximum number of users")
+	cfg.Init()
+
+	if err := cfg.Load(); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected flag.ErrHelp.\nBut got: %v\n", err)
+	}
+	expected := "Usage of test:\n" +
+		"  max-users int (default 60)\n" +
+		"    \tMaximum number of users\n" +
+		"    \tset by: -max-users\n"
+	if output.String() != expected {
+		t.Errorf("Expected usage:\n%s\nBut got:\n%s\n", expected, output.String())
+	}
+}
+
+// TestSource_Load_Lazy tests that the flags are parsed once when they are
+// used to build a lazy source, so errors and the usage are printed once.
+func TestSource_Load_Lazy(t *testing.T) {
+	var output bytes.Buffer
+	set := newTestFlagSet()
+	set.SetOutput(&output)
+	args := []string{"-config", "app.ini", "-port", "9090", "-bogus"}
+	var path string
+	cfg := congo.New("test",
+		FromFlagSet(set, func() []string { return args }),
+		congo.Lazy(func() (congo.Source, error) {
+			return mapsource.New(map[string]string{"port": "80", "host": path}), nil
+		}),
+	)
+	cfg.StringVar(&path, "config", "", "")
+	port := cfg.Int("port", 0, "")
+	host := cfg.String("host", "", "")
+	cfg.Init()
+
+	if err := cfg.Load(); err == nil {
+		t.Errorf("Expected an error for the unknown flag.\n")
+	}
+	if n := strings.Count(output.String(), "flag provided but not defined"); n != 1 {
+		t.Errorf("Expected the error to be printed once.\nBut got:\n%s\n", output.String())
+	}
+	if n := strings.Count(output.String(), "Usage of test:"); n != 1 {
+		t.Errorf("Expected the usage to be printed once.\nBut got:\n%s\n", output.String())
+	}
+
+	output.Reset()
+	args = args[:4]
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *port != 9090 || *host != "app.ini" {
+		t.Errorf("Expected port 9090 of the flag and host app.ini.\nBut got: %d and %s\n", *port, *host)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected nothing to be printed.\nBut got:\n%s\n", output.String())
+	}
+}
+
+// TestSource_Groups tests that groups aren't turned into flags.
+func TestSource_Groups(t *testing.T) {
+	var output bytes.Buffer
+	set := newTestFlagSet()
+	set.SetOutput(&output)
+	src := FromFlagSet(set, func() []string { return []string{"-upstream=q"} })
+	cfg := congo.New("test", src).Using(&struct {
+		Upstreams []struct {
+			Host string `name:"host"`
+		} `name:"upstream"`
+	}{})
+	cfg.Init()
+
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), "flag provided but not defined") {
+		t.Errorf("Expected -upstream to be unknown.\nBut got: %v\n", err)
+	}
+	if strings.Contains(output.String(), "set by: -upstream") {
+		t.Errorf("Expected the usage not to list -upstream.\nBut got:\n%s\n", output.String())
+	}
+	if names := src.(congo.Namer).Names("upstream"); len(names) != 0 {
+		t.Errorf("Expected no flag to set the group.\nBut got: %v\n", names)
+	}
+}
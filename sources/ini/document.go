@@ -0,0 +1,76 @@
+package ini
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// document indexes the lines keys are defined on in the content
+// of an input.
+type document struct {
+	name  string
+	lines map[string]map[string][]int // section -> key -> lines (shadowed keys have several)
+}
+
+// defaultSection is the name go-ini uses for the section
+// before the first section header.
+const defaultSection = "DEFAULT"
+
+// index scans data for section headers and keys and records the
+// lines they are defined on.
+func index(name string, data []byte) *document {
+	d := &document{name, make(map[string]map[string][]int)}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[':
+			if end := strings.IndexByte(line, ']'); end > 0 {
+				section = strings.TrimSpace(line[1:end])
+				if section == defaultSection {
+					section = ""
+				}
+			}
+			continue
+		}
+		end := strings.IndexAny(line, "=:")
+		if end < 0 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(line[:end]), "\"`")
+		if d.lines[section] == nil {
+			d.lines[section] = make(map[string][]int)
+		}
+		d.lines[section][key] = append(d.lines[section][key], n)
+	}
+	return d
+}
+
+// location describes where the i-th value of given key was defined.
+// Negative indices count from the last value backwards.
+func (d *document) location(section string, key string, i int) string {
+	if section == defaultSection {
+		section = ""
+	}
+	lines := d.lines[section][key]
+	if i < 0 {
+		i += len(lines)
+	}
+	if i < 0 || i >= len(lines) {
+		return d.name
+	}
+	return fmt.Sprintf("%s:%d", d.name, lines[i])
+}
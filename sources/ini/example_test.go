@@ -58,3 +58,39 @@ func Example() {
 	//Using number 54 and decimal 0.500000
 	//2h45m0s
 }
+
+// Example_using shows how one ini source sets the settings of all sections.
+// Section names are the prefixes of the setting names.
+func Example_using() {
+	type Config struct {
+		Debug  bool `name:"debug"`
+		Server struct {
+			Host string `name:"host"`
+			Port int    `name:"port"`
+		} `name:"server"`
+		Database struct {
+			Pool struct {
+				MaxSize int `name:"max-size"`
+			} `name:"pool"`
+		} `name:"database"`
+	}
+	content := "" +
+		"debug = true\n" +
+		"[server]\n" +
+		"host = example.com\n" +
+		"port = 8080\n" +
+		"[database.pool]\n" +
+		"max-size = 20\n"
+
+	config := &Config{}
+	cfg := congo.New("main", FromBytes([]byte(content)))
+	cfg.Using(config)
+
+	cfg.Init()
+	cfg.Load()
+
+	fmt.Printf("debug=%t server=%s:%d pool=%d\n",
+		config.Debug, config.Server.Host, config.Server.Port, config.Database.Pool.MaxSize)
+	//Output:
+	//debug=true server=example.com:8080 pool=20
+}
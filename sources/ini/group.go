@@ -0,0 +1,72 @@
+package ini
+
+import (
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// path returns the name of the section holding the setting with given name
+// as sub-section e.g. the entries of a group.
+func (s *iniSource) path(name string) string {
+	if s.section == "" {
+		return name
+	}
+	return s.section + congo.NameSeparator + name
+}
+
+// groupSections returns the keys of the entries of the group whose sections
+// start with given prefix in the order they appear and the section of each
+// entry. Entries are given as [prefix "key"] or as sub-section [prefix.key].
+// The sub-sections of both (e.g. [prefix "key".tls]) belong to the entry.
+func groupSections(layers []layer, prefix string) ([]string, map[string]string) {
+	var keys []string
+	sections := make(map[string]string)
+	for _, l := range layers {
+		for _, name := range l.cfg.SectionStrings() {
+			var key, section string
+			switch {
+			case strings.HasPrefix(name, prefix+` "`):
+				rest := name[len(prefix)+2:]
+				end := strings.IndexByte(rest, '"')
+				if end < 0 || end+1 < len(rest) && !strings.HasPrefix(rest[end+1:], congo.NameSeparator) {
+					continue
+				}
+				key, section = rest[:end], name[:len(prefix)+end+3]
+			case strings.HasPrefix(name, prefix+congo.NameSeparator):
+				key = name[len(prefix)+len(congo.NameSeparator):]
+				if i := strings.Index(key, congo.NameSeparator); i >= 0 {
+					key = key[:i]
+				}
+				section = prefix + congo.NameSeparator + key
+			default:
+				continue
+			}
+			if _, ok := sections[key]; !ok && key != "" {
+				keys = append(keys, key)
+				sections[key] = section
+			}
+		}
+	}
+	return keys, sections
+}
+
+// loadGroup creates the entries of the group of the setting with given name
+// for the sections found in given layers and sets their settings.
+func (s *iniSource) loadGroup(layers []layer, name string, group congo.GroupValue) error {
+	var errs congo.Errors
+	keys, sections := groupSections(layers, s.path(name))
+	for _, key := range keys {
+		entry := &iniSource{section: sections[key]}
+		errs.Append(entry.load(layers, group.Entry(key)).Err())
+	}
+	return errs.Err()
+}
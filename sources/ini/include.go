@@ -0,0 +1,108 @@
+package ini
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// maxIncludeDepth is the maximum number of files including each other.
+const maxIncludeDepth = 10
+
+// directive returns the path an include directive on given trimmed line
+// refers to. Returns false if the line isn't an include directive.
+func directive(line string) (string, bool) {
+	if !strings.HasPrefix(line, "!include ") && !strings.HasPrefix(line, "!include\t") {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(line[len("!include"):]), "\"`"), true
+}
+
+// parse parses the ini data with given name. Relative paths of include
+// directives are resolved against dir. The files included are parsed first,
+// so keys defined in the data override the keys of included files.
+// Files are identified by their absolute paths in stack to detect cycles.
+// Depth is the number of files including each other up to the data, which
+// also counts data that isn't read from a file.
+func parse(name string, dir string, data []byte, stack []string, depth int) ([]layer, error) {
+	lines := strings.Split(string(data), "\n")
+	var layers []layer
+	for i, line := range lines {
+		path, ok := directive(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		// The directive is removed but the line kept, so locations don't change.
+		lines[i] = ""
+		included, err := include(fmt.Sprintf("%s:%d", name, i+1), dir, path, stack, depth)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, included...)
+	}
+	data = []byte(strings.Join(lines, "\n"))
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowShadows: true}, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return append(layers, layer{cfg, index(name, data)}), nil
+}
+
+// include parses the files matching given path which is included at given
+// location. Paths containing wildcards (see filepath.Match) include all
+// matching files in lexical order; all other paths must exist.
+func include(location string, dir string, path string, stack []string, depth int) ([]layer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%s: include directive without path", location)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	paths := []string{path}
+	if strings.ContainsAny(path, "*?[") {
+		var err error
+		if paths, err = filepath.Glob(path); err != nil {
+			return nil, fmt.Errorf("%s: couldn't include %s: %w", location, path, err)
+		}
+		sort.Strings(paths)
+	}
+	var layers []layer
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: couldn't include %s: %w", location, path, err)
+		}
+		for i, included := range stack {
+			if included == abs {
+				cycle := strings.Join(append(stack[i:len(stack):len(stack)], abs), " -> ")
+				return nil, fmt.Errorf("%s: include cycle %s", location, cycle)
+			}
+		}
+		if depth >= maxIncludeDepth {
+			return nil, fmt.Errorf("%s: couldn't include %s: includes are nested deeper than %d files",
+				location, path, maxIncludeDepth)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: couldn't include %s: %w", location, path, err)
+		}
+		included, err := parse(path, filepath.Dir(path), data, append(stack[:len(stack):len(stack)], abs), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, included...)
+	}
+	return layers, nil
+}
@@ -7,7 +7,13 @@ import (
 
 	"fmt"
 
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/go-ini/ini"
+
+	"gitlab.com/silentteacup/congo/internal/input"
 )
 
 /*
@@ -36,25 +42,81 @@ func FromFile(path string) Source {
 	return createSource(path)
 }
 
+// FromGlob creates a new ini source which loads the configuration from
+// all files matching given pattern (see filepath.Match) in lexical order
+// of their paths. Files loaded later override the keys of earlier files,
+// like the drop-ins of systemd:
+//
+//	ini.FromGlob("/etc/app/conf.d/*.ini")
+//
+// Settings are only set from the last file containing their key. The origin
+// of a setting names the file it was set from.
+func FromGlob(pattern string) Source {
+	return &iniSource{globInputs(pattern), "", true, false, nil}
+}
+
+// FromDir creates a new ini source which loads the configuration from
+// all files ending with ".ini" in the directory at given path
+// (see FromGlob).
+func FromDir(path string) Source {
+	return FromGlob(filepath.Join(path, "*.ini"))
+}
+
 // createSource creates the ini source with default values
 // using given source as source for the ini-file.
 func createSource(source interface{}) Source {
-	return &iniSource{source, "", true, nil}
+	in := input.New(source)
+	inputs := func(bool) ([]*input.Input, error) {
+		return []*input.Input{in}, nil
+	}
+	return &iniSource{inputs, "", true, false, nil}
 }
 
+// globInputs returns the inputs of the files matching given pattern. If loose
+// is set the source doesn't complain if no file matches.
+func globInputs(pattern string) func(loose bool) ([]*input.Input, error) {
+	return func(loose bool) ([]*input.Input, error) {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 && !loose {
+			return nil, fmt.Errorf("no files match %s", pattern)
+		}
+		sort.Strings(paths)
+		inputs := make([]*input.Input, len(paths))
+		for i, path := range paths {
+			inputs[i] = input.New(path)
+		}
+		return inputs, nil
+	}
+}
+
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "ini"
+
 // Source a ini source uses input in ini-syntax
 // to load settings.
+//
+// The input can include other files using "!include path" on a line of its
+// own. Relative paths are resolved against the directory of the including
+// file and may contain wildcards to include several files. Included files are
+// loaded before the file including them, so its keys override theirs. Includes
+// may be nested up to 10 files deep; cycles are reported as error.
 type Source interface {
 	congo.Source
 	Section(name string) Source
 	WriteDefaults(w io.Writer) error
+	WriteCurrent(w io.Writer, redact bool) error
 	SetLooseLoad(loose bool) Source
+	SetStrict(strict bool) Source
 }
 
 type iniSource struct {
-	source    interface{}
+	inputs    func(loose bool) ([]*input.Input, error)
 	section   string
 	looseLoad bool
+	strict    bool
 	defaults  map[string]*congo.Setting
 }
 
@@ -64,74 +126,305 @@ func (s *iniSource) Init(settings map[string]*congo.Setting) error {
 	return nil
 }
 
-// loadIni loads the ini file in the appropriate way.
-func (s *iniSource) loadIni() (cfg *ini.File, err error) {
-	if s.looseLoad {
-		cfg, err = ini.LooseLoad(s.source)
-	} else {
-		cfg, err = ini.Load(s.source)
+// layer is a loaded ini file. The document is used to locate the keys
+// in the file.
+type layer struct {
+	cfg *ini.File
+	doc *document
+}
+
+// loadIni loads the ini files in the appropriate way.
+// Keys may be repeated (shadowed) to provide several values for a setting.
+// Files loaded later override the keys of earlier files. Files included by
+// a file are loaded before it (see parse).
+func (s *iniSource) loadIni() ([]layer, error) {
+	inputs, err := s.inputs(s.looseLoad)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]layer, 0, len(inputs))
+	for _, in := range inputs {
+		data, err := in.Content(s.looseLoad)
+		if err != nil {
+			return nil, err
+		}
+		// Relative includes of readers and content are resolved
+		// against the working directory.
+		dir, stack := ".", []string(nil)
+		if path, ok := in.Path(); ok {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			dir, stack = filepath.Dir(path), []string{abs}
+		}
+		parsed, err := parse(in.Name(), dir, data, stack, 1)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, parsed...)
+	}
+	return layers, nil
+}
+
+// lookup returns the key at given location of the last layer containing it.
+// Returns false if no layer contains the key.
+func lookup(layers []layer, sectionName string, key string) (*ini.Key, layer, bool) {
+	for i := len(layers) - 1; i >= 0; i-- {
+		section, err := layers[i].cfg.GetSection(sectionName)
+		if err != nil || !section.HasKey(key) {
+			continue
+		}
+		if k, err := section.GetKey(key); err == nil {
+			return k, layers[i], true
+		}
+	}
+	return nil, layer{}, false
+}
+
+// set sets the setting to the values of given key. Shadowed values are
+// appended to settings holding several elements; all other settings use
+// the last value given.
+func set(setting *congo.Setting, k *ini.Key, doc *document, section string) error {
+	values := k.ValueWithShadows()
+	if len(values) == 0 {
+		// Empty values have no shadows.
+		values = []string{k.Value()}
+	}
+	if _, ok := setting.Value.(congo.SliceValue); !ok {
+		origin := congo.Origin{Source: sourceName, Location: doc.location(section, k.Name(), -1)}
+		return setting.Set(values[len(values)-1], origin)
+	}
+	for i, v := range values {
+		origin := congo.Origin{Source: sourceName, Location: doc.location(section, k.Name(), i)}
+		if i == 0 {
+			if err := setting.Set(v, origin); err != nil {
+				return err
+			}
+		} else if err := setting.Append(v, origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locate resolves the section and key a setting with given name is stored in.
+// Hierarchical names are split at their last congo.NameSeparator: the front
+// part names a (sub-)section of this source's section, the rest names the key.
+func (s *iniSource) locate(name string) (section string, key string) {
+	i := strings.LastIndex(name, congo.NameSeparator)
+	if i < 0 {
+		return s.section, name
+	}
+	section, key = name[:i], name[i+len(congo.NameSeparator):]
+	if s.section != "" {
+		section = s.section + congo.NameSeparator + section
+	}
+	return section, key
+}
+
+// Names returns the key that sets the setting with given name. Keys outside
+// of the default section are preceded by their section e.g. "[server] port".
+// Groups are set by the sections of their entries e.g. [upstream "<key>"].
+func (s *iniSource) Names(setting string) []string {
+	if group, ok := s.defaults[setting]; ok {
+		if _, ok := group.Value.(congo.GroupValue); ok {
+			return []string{"[" + s.path(setting) + ` "<key>"]`}
+		}
+	}
+	return []string{keyName(s.locate(setting))}
+}
+
+// keyName returns the name of a key that includes its section.
+func keyName(section string, key string) string {
+	if section == "" || section == defaultSection {
+		return key
 	}
-	return cfg, err
+	return "[" + section + "] " + key
 }
 
 // Load loads the settings from input in ini-syntax.
+// All settings that can't be set are reported as congo.Errors.
 func (s *iniSource) Load(settings map[string]*congo.Setting) error {
-	cfg, err := s.loadIni()
+	layers, err := s.loadIni()
 	if err != nil {
-		return fmt.Errorf("ini-source: couldn't load the ini-file because: %s", err)
+		return fmt.Errorf("ini-source: couldn't load the ini-file because: %w", err)
 	}
-	section, err := cfg.GetSection(s.section)
-	if err != nil {
-		// Section doesn't exist
-		// We simply don't load the section and use the defaults
-		return nil
+	errs := s.load(layers, settings)
+	if s.strict {
+		known := s.known(layers, settings)
+		for _, l := range layers {
+			errs.Append(s.checkUnknown(l.cfg, l.doc, known))
+		}
 	}
-	for key, setting := range settings {
-		if !section.HasKey(key) {
+	return errs.Err()
+}
+
+// load sets the settings from given layers. Settings holding groups
+// are set from the sections of their entries (see loadGroup).
+func (s *iniSource) load(layers []layer, settings map[string]*congo.Setting) congo.Errors {
+	var errs congo.Errors
+	for _, name := range congo.Names(settings) {
+		if group, ok := settings[name].Value.(congo.GroupValue); ok {
+			errs.Append(s.loadGroup(layers, name, group))
 			continue
 		}
-		k, err := section.GetKey(key)
-		if err != nil {
-			// Key exists but can't get key
-			// Return error
-			return err
+		sectionName, key := s.locate(name)
+		k, l, ok := lookup(layers, sectionName, key)
+		if !ok {
+			// Neither section nor key exist
+			// We simply don't load the setting and use the default
+			continue
 		}
-		if err := setting.Value.Set(k.Value()); err != nil {
-			return fmt.Errorf("ini-source: couldn't read setting %q "+
-				"in section %q: %s", key, s.section, err)
+		errs.Append(set(settings[name], k, l.doc, sectionName))
+	}
+	return errs
+}
+
+// known returns the names of the keys of given settings including the keys
+// of the entries of groups found in given layers.
+func (s *iniSource) known(layers []layer, settings map[string]*congo.Setting) []string {
+	var known []string
+	for _, name := range congo.Names(settings) {
+		group, ok := settings[name].Value.(congo.GroupValue)
+		if !ok {
+			known = append(known, keyName(s.locate(name)))
+			continue
+		}
+		keys, sections := groupSections(layers, s.path(name))
+		for _, key := range keys {
+			entry := &iniSource{section: sections[key]}
+			known = append(known, entry.known(layers, group.Entry(key))...)
 		}
 	}
-	return nil
+	return known
+}
+
+// checkUnknown reports all keys of this source's section and its sub-sections
+// that aren't known as *congo.UnknownError.
+func (s *iniSource) checkUnknown(cfg *ini.File, doc *document, known []string) error {
+	isKnown := make(map[string]bool, len(known))
+	for _, key := range known {
+		isKnown[key] = true
+	}
+	var errs congo.Errors
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		if name == defaultSection {
+			name = ""
+		}
+		if s.section != "" && name != s.section && !strings.HasPrefix(name, s.section+congo.NameSeparator) {
+			continue
+		}
+		for _, k := range section.Keys() {
+			key := keyName(name, k.Name())
+			if isKnown[key] {
+				continue
+			}
+			errs.Append(&congo.UnknownError{
+				Source:     sourceName,
+				Key:        key,
+				Location:   doc.location(name, k.Name(), 0),
+				Suggestion: congo.Suggest(key, known),
+			})
+		}
+	}
+	return errs.Err()
 }
 
 // WriteDefaults writes the default settings to given writer.
 // If an error occurs nothing will be written.
 func (s *iniSource) WriteDefaults(w io.Writer) (err error) {
 	cfg := ini.Empty()
-	section := cfg.Section(s.section)
-	for name, setting := range s.defaults {
-		k := section.Key(name)
-		k.Comment = setting.Usage
-		k.SetValue(setting.DefValue)
+	if err := s.add(cfg, s.defaults, false, false); err != nil {
+		return err
 	}
 	_, err = cfg.WriteTo(w)
 	return err
 }
 
+// redacted replaces the values of secret settings written by WriteCurrent.
+const redacted = "<redacted>"
+
+// WriteCurrent writes the current values of the settings to given writer,
+// e.g. after they were loaded from all sources, in the same format as
+// WriteDefaults. The entries of groups are written as sections
+// [group "key"]. If redact is set the values of secret settings
+// (see congo.Secret) are replaced by "<redacted>".
+// If an error occurs nothing will be written.
+//
+// Settings may be reloaded concurrently, so WriteCurrent should
+// be called within congo.Congo.Read.
+func (s *iniSource) WriteCurrent(w io.Writer, redact bool) error {
+	cfg := ini.Empty()
+	if err := s.add(cfg, s.defaults, true, redact); err != nil {
+		return err
+	}
+	_, err := cfg.WriteTo(w)
+	return err
+}
+
+// add adds keys for given settings to cfg. The keys hold the current values
+// if current is set and the default values otherwise.
+func (s *iniSource) add(cfg *ini.File, settings map[string]*congo.Setting, current bool, redact bool) error {
+	// Sorted names make sure sections and keys are always written in the same order.
+	for _, name := range congo.Names(settings) {
+		setting := settings[name]
+		if group, ok := setting.Value.(congo.GroupValue); ok {
+			if !current {
+				continue
+			}
+			for _, key := range group.Keys() {
+				entry := &iniSource{section: s.path(name) + ` "` + key + `"`}
+				if err := entry.add(cfg, group.Entry(key), current, redact); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		value := setting.DefValue
+		switch {
+		case current && redact && setting.Secret:
+			value = redacted
+		case current:
+			value = setting.Value.String()
+		}
+		sectionName, key := s.locate(name)
+		// NewKey doesn't fall back to keys of parent sections like Key does.
+		k, err := cfg.Section(sectionName).NewKey(key, value)
+		if err != nil {
+			return err
+		}
+		k.Comment = setting.Help()
+	}
+	return nil
+}
+
 // SetLooseLoad sets whether this source should complain if the file
-// doesn't exist. Default is true.
+// doesn't exist or no file matches the pattern given to FromGlob.
+// Default is true.
 func (s *iniSource) SetLooseLoad(loose bool) Source {
 	s.looseLoad = loose
 	return s
 }
 
+// SetStrict sets whether this source should report keys that don't belong
+// to any setting (e.g. misspelled keys) as *congo.UnknownError. Keys of the
+// section of this source and all its sub-sections are checked, so a source
+// without a section checks the whole input. Default is false.
+func (s *iniSource) SetStrict(strict bool) Source {
+	s.strict = strict
+	return s
+}
+
 // Section creates a sub-source that loads settings from a section
 // of the ini input.
 func (s *iniSource) Section(name string) Source {
 	return &iniSource{
-		s.source,
+		s.inputs,
 		name,
 		s.looseLoad,
+		s.strict,
 		s.defaults,
 	}
 }
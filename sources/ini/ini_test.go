@@ -2,9 +2,11 @@ package ini
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -197,9 +199,8 @@ func TestIniSource_Load(t *testing.T) {
 func TestIniSource_Load_NotLoose(t *testing.T) {
 	s := FromFile("")
 	err := s.SetLooseLoad(false).Load(make(map[string]*congo.Setting))
-	if err == nil {
-		t.Errorf("Expected non-loose load to return error.\n" +
-			"But no error was returned.\n")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected non-loose load to return os.ErrNotExist.\nBut got: %v\n", err)
 	}
 }
 
@@ -257,6 +258,10 @@ func TestIniSource_Load_InvalidValue(t *testing.T) {
 		t.Errorf("Expected invalid value to cause return error on load.\n" +
 			"But no error was returned.\n")
 	}
+	var loadErr *congo.LoadError
+	if !errors.As(err, &loadErr) || loadErr.Location != "<bytes>:1" || loadErr.Raw != "invalid" {
+		t.Errorf("Expected error to describe the invalid value.\nBut got: %v\n", err)
+	}
 }
 
 // TestIniSource_WriteDefaults test the writing of defaults for
@@ -291,3 +296,431 @@ func TestIniSource_WriteDefaults(t *testing.T) {
 			actual)
 	}
 }
+
+// TestIniSource_Load_Nested tests that hierarchical setting names are
+// resolved using (sub-)sections.
+func TestIniSource_Load_Nested(t *testing.T) {
+	v := &mockValue{}
+	w := &mockValue{}
+	settings := map[string]*congo.Setting{
+		"pool.max-size": {
+			Name:     "pool.max-size",
+			Value:    v,
+			DefValue: "0",
+		},
+		"max-size": {
+			Name:     "max-size",
+			Value:    w,
+			DefValue: "0",
+		},
+	}
+	s := FromBytes([]byte("" +
+		"[database]\n" +
+		"max-size=3\n" +
+		"[database.pool]\n" +
+		"max-size=20\n")).Section("database")
+	if err := s.Load(settings); err != nil {
+		t.Errorf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if v.SetParam != "20" {
+		t.Errorf("Expected Set() to be called with %s as parameter"+
+			".\nBut was called with %s.\n", "20", v.SetParam)
+	}
+	if w.SetParam != "3" {
+		t.Errorf("Expected Set() to be called with %s as parameter"+
+			".\nBut was called with %s.\n", "3", w.SetParam)
+	}
+
+	s.Init(settings)
+	out := bytes.NewBufferString("")
+	s.WriteDefaults(out)
+	expected := "[database]\n" +
+		"max-size = 0\n\n" +
+		"[database.pool]\n" +
+		"max-size = 0"
+	actual := strings.Trim(out.String(), "\n ")
+	if actual != expected {
+		t.Errorf("Expected written default to be:\n %q\nBut was:\n %q\n",
+			expected,
+			actual)
+	}
+}
+
+// TestIniSource_Load_Shadows tests that shadowed keys are appended to
+// slice settings and overwrite all other settings.
+func TestIniSource_Load_Shadows(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(""+
+		"tag=a\n"+
+		"tag=b,c\n"+
+		"number=1\n"+
+		"number=2\n")))
+	tags := cfg.StringSlice("tag", nil, "")
+	number := cfg.Int("number", 0, "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if len(*tags) != 3 || (*tags)[0] != "a" || (*tags)[2] != "c" {
+		t.Errorf("Expected shadowed keys to be appended.\nBut got: %v\n", *tags)
+	}
+	if *number != 2 {
+		t.Errorf("Expected last value to be used.\nBut got: %d\n", *number)
+	}
+}
+
+// TestIniSource_Load_Origin tests that the file and line of a key are
+// recorded as origin of a setting.
+func TestIniSource_Load_Origin(t *testing.T) {
+	defer cleanUp(createTmpFiles(contentTmpl)...)
+	cfg := congo.New("test", FromFile(contentTmpl.Path()))
+	cfg.Int("number", 0, "")
+	cfg.Duration("section.duration", 0, "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	expected := map[string]congo.Origin{
+		"number":           {Source: "ini", Raw: "54", Location: contentTmpl.Path() + ":3"},
+		"section.duration": {Source: "ini", Raw: "2h45m", Location: contentTmpl.Path() + ":7"},
+	}
+	for name, e := range expected {
+		if origin, _ := cfg.Origin(name); origin != e {
+			t.Errorf("Expected origin of %q to be %+v.\nBut got: %+v\n", name, e, origin)
+		}
+	}
+}
+
+func TestIniSource_Names(t *testing.T) {
+	src := FromBytes(nil)
+	tests := []struct {
+		source   Source
+		setting  string
+		expected string
+	}{
+		{src, "port", "port"},
+		{src, "database.pool.max-size", "[database.pool] max-size"},
+		{src.Section("server"), "port", "[server] port"},
+		{src.Section("server"), "tls.cert", "[server.tls] cert"},
+	}
+	for _, test := range tests {
+		names := test.source.(congo.Namer).Names(test.setting)
+		if len(names) != 1 || names[0] != test.expected {
+			t.Errorf("Expected %q to be named %q.\nBut got: %v\n", test.setting, test.expected, names)
+		}
+	}
+}
+
+func TestIniSource_Load_Strict(t *testing.T) {
+	content := "debug = true\n[server]\nport = 80\nmax-user = 10\n[server.tls]\ncert = a\n[other]\nkey = b\n"
+	cfg := congo.New("test", FromBytes([]byte(content)).Section("server").SetStrict(true))
+	cfg.Int("port", 0, "")
+	cfg.Int("max-users", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var errs congo.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected the unknown keys of the section to be reported.\nBut got: %v\n", err)
+	}
+	expected := []congo.UnknownError{
+		{Source: "ini", Key: "[server] max-user", Location: "<bytes>:4", Suggestion: "[server] max-users"},
+		{Source: "ini", Key: "[server.tls] cert", Location: "<bytes>:6"},
+	}
+	for i, err := range errs {
+		unknown, ok := err.(*congo.UnknownError)
+		if !ok || *unknown != expected[i] {
+			t.Errorf("Expected error %+v.\nBut got: %v\n", expected[i], err)
+		}
+	}
+}
+
+// TestFromDir tests that drop-ins override each other in lexical order and
+// the origin of a setting names the drop-in it was set from.
+func TestFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "congo")
+	if err != nil {
+		t.Fatalf("Couldn't create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(filepath.Join(dir, "10-base.ini"), []byte("debug = true\n[server]\nport = 80\nhost = localhost\n"))
+	writeFile(filepath.Join(dir, "20-port.ini"), []byte("[server]\n\nport = 8080\n"))
+	writeFile(filepath.Join(dir, "ignored.conf"), []byte("[server]\nport = 1\n"))
+
+	cfg := congo.New("test", FromDir(dir).Section("server"))
+	port := cfg.Int("port", 0, "")
+	host := cfg.String("host", "", "")
+	debug := cfg.Bool("debug", false, "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *port != 8080 || *host != "localhost" || *debug {
+		t.Errorf("Expected port 8080, host localhost and debug false.\nBut got: %d, %s and %t\n",
+			*port, *host, *debug)
+	}
+	expected := map[string]string{
+		"port": filepath.Join(dir, "20-port.ini") + ":3",
+		"host": filepath.Join(dir, "10-base.ini") + ":4",
+	}
+	for name, location := range expected {
+		if origin, _ := cfg.Origin(name); origin.Location != location {
+			t.Errorf("Expected %q to be set at %s.\nBut got: %+v\n", name, location, origin)
+		}
+	}
+}
+
+func TestFromGlob_NotLoose(t *testing.T) {
+	pattern := filepath.Join(os.TempDir(), "congo-missing-*.ini")
+	cfg := congo.New("test", FromGlob(pattern))
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Expected missing files to be ignored.\nBut got error: %s\n", err)
+	}
+	cfg = congo.New("test", FromGlob(pattern).SetLooseLoad(false))
+	cfg.Init()
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), pattern) {
+		t.Errorf("Expected an error naming the pattern.\nBut got: %v\n", err)
+	}
+}
+
+// TestIniSource_Load_Include tests that included files are loaded before
+// the file including them.
+func TestIniSource_Load_Include(t *testing.T) {
+	dir, err := ioutil.TempDir("", "congo")
+	if err != nil {
+		t.Fatalf("Couldn't create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "conf.d"), 0755)
+	writeFile(filepath.Join(dir, "main.ini"),
+		[]byte("include = main\n!include common.ini\n[server]\nport = 80\n!include conf.d/*.ini\n"))
+	writeFile(filepath.Join(dir, "common.ini"), []byte("[server]\nport = 1\nhost = localhost\n"))
+	writeFile(filepath.Join(dir, "conf.d", "tls.ini"), []byte("[server.tls]\ncert = server.pem\n"))
+
+	main := FromFile(filepath.Join(dir, "main.ini"))
+	cfg := congo.New("test", main.Section("server").SetStrict(true))
+	port := cfg.Int("port", 0, "")
+	host := cfg.String("host", "", "")
+	cert := cfg.String("tls.cert", "", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *port != 80 || *host != "localhost" || *cert != "server.pem" {
+		t.Errorf("Expected port 80, host localhost and cert server.pem.\nBut got: %d, %s and %s\n",
+			*port, *host, *cert)
+	}
+	location := filepath.Join(dir, "conf.d", "tls.ini") + ":2"
+	if origin, _ := cfg.Origin("tls.cert"); origin.Location != location {
+		t.Errorf("Expected cert to be set at %s.\nBut got: %+v\n", location, origin)
+	}
+
+	// Only !include is a directive, keys named include are ordinary keys.
+	cfg = congo.New("test", main.Section(""))
+	include := cfg.String("include", "", "")
+	cfg.Init()
+	cfg.Load()
+	if *include != "main" {
+		t.Errorf("Expected the key include to be main.\nBut got: %q\n", *include)
+	}
+}
+
+func TestIniSource_Load_IncludeErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "congo")
+	if err != nil {
+		t.Fatalf("Couldn't create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(filepath.Join(dir, "missing.ini"), []byte("a = 1\n!include other.ini\n"))
+	writeFile(filepath.Join(dir, "a.ini"), []byte("!include b.ini\n"))
+	writeFile(filepath.Join(dir, "b.ini"), []byte("\n!include a.ini\n"))
+	writeFile(filepath.Join(dir, "self.ini"), []byte("!include self.ini\n"))
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"missing.ini", filepath.Join(dir, "missing.ini") + ":2: couldn't include " + filepath.Join(dir, "other.ini")},
+		{"a.ini", filepath.Join(dir, "b.ini") + ":2: include cycle " +
+			filepath.Join(dir, "a.ini") + " -> " + filepath.Join(dir, "b.ini") + " -> " + filepath.Join(dir, "a.ini")},
+		{"self.ini", "include cycle"},
+	}
+	for _, test := range tests {
+		cfg := congo.New("test", FromFile(filepath.Join(dir, test.file)))
+		cfg.Init()
+		if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q.\nBut got: %v\n", test.expected, err)
+		}
+	}
+
+	cfg := congo.New("test", FromFile(filepath.Join(dir, "missing.ini")))
+	cfg.Init()
+	if err := cfg.Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the missing include to be os.ErrNotExist.\nBut got: %v\n", err)
+	}
+
+	// Each file includes the previous one. The content and the files it
+	// includes may be maxIncludeDepth files, whether it is read from a file or not.
+	content := []byte("a = 1\n")
+	for i := 1; i < maxIncludeDepth; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("level%d.ini", i))
+		writeFile(name, content)
+		content = []byte("!include " + name + "\n")
+	}
+	root := filepath.Join(dir, "root.ini")
+	writeFile(root, content)
+	for _, src := range []Source{FromBytes(content), FromFile(root)} {
+		cfg := congo.New("test", src)
+		cfg.Init()
+		if err := cfg.Load(); err != nil {
+			t.Errorf("Expected %d nested files to load without problems.\nBut got error: %s\n", maxIncludeDepth, err)
+		}
+	}
+	content = []byte("!include " + root + "\n")
+	writeFile(root+".2", content)
+	for _, src := range []Source{FromBytes(content), FromFile(root + ".2")} {
+		cfg := congo.New("test", src)
+		cfg.Init()
+		if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), "nested deeper than") {
+			t.Errorf("Expected an error about the depth of the includes.\nBut got: %v\n", err)
+		}
+	}
+}
+
+// TestIniSource_Load_AllSections tests that a source without section sets
+// the settings of all sections and the default section sets top-level names.
+func TestIniSource_Load_AllSections(t *testing.T) {
+	content := "[DEFAULT]\nname = congo\n[server]\nport = 80\n[server.tls]\ncert = server.pem\n"
+	cfg := congo.New("test", FromBytes([]byte(content)).SetStrict(true))
+	name := cfg.String("name", "", "")
+	port := cfg.Int("server.port", 0, "")
+	cert := cfg.String("server.tls.cert", "", "")
+	missing := cfg.String("server.name", "default", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *name != "congo" || *port != 80 || *cert != "server.pem" {
+		t.Errorf("Expected name congo, port 80 and cert server.pem.\nBut got: %s, %d and %s\n", *name, *port, *cert)
+	}
+	if *missing != "default" {
+		t.Errorf("Expected keys of the default section not to be inherited by sections.\nBut got: %s\n", *missing)
+	}
+}
+
+type upstream struct {
+	Host string `name:"host"`
+	Port int    `name:"port"`
+	TLS  struct {
+		Cert string `name:"cert"`
+	} `name:"tls"`
+}
+
+// TestIniSource_Load_Groups tests that repeated sections set the entries
+// of map and slice fields.
+func TestIniSource_Load_Groups(t *testing.T) {
+	content := "" +
+		"[upstream \"b\"]\n" +
+		"host = b.example.com\n" +
+		"[upstream \"a\"]\n" +
+		"host = a.example.com\n" +
+		"port = 8080\n" +
+		"[server.backend.x]\n" +
+		"host = x\n" +
+		"[server.backend.x.tls]\n" +
+		"cert = x.pem\n" +
+		"[server]\n" +
+		"port = 80\n"
+	config := &struct {
+		Upstreams map[string]upstream `name:"upstream"`
+		Backends  []upstream          `name:"backend"`
+		Port      int                 `name:"port"`
+	}{}
+	src := FromBytes([]byte(content)).SetStrict(true)
+	cfg := congo.New("test", src).Using(config)
+	cfg.Init()
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), `unknown key "[server] port"`) {
+		t.Errorf("Expected the keys of [server] to be unknown.\nBut got: %v\n", err)
+	}
+	expected := map[string]upstream{"a": {Host: "a.example.com", Port: 8080}, "b": {Host: "b.example.com"}}
+	if !reflect.DeepEqual(config.Upstreams, expected) {
+		t.Errorf("Expected upstreams %+v.\nBut got: %+v\n", expected, config.Upstreams)
+	}
+	if origin, ok := cfg.Origin("upstream.a.port"); !ok || origin.Raw != "8080" || !cfg.IsSet("upstream.a.port") {
+		t.Errorf("Expected upstream.a.port to originate from the file.\nBut got: %v\n", origin)
+	}
+	if cfg.IsSet("upstream.b.port") {
+		t.Errorf("Expected upstream.b.port not to be set.\n")
+	}
+	if names := src.(congo.Namer).Names("upstream"); !reflect.DeepEqual(names, []string{`[upstream "<key>"]`}) {
+		t.Errorf("Expected the group to be set by [upstream \"<key>\"].\nBut got: %v\n", names)
+	}
+
+	cfg = congo.New("test", src.Section("server")).Using(config)
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if len(config.Backends) != 1 || config.Backends[0].Host != "x" || config.Backends[0].TLS.Cert != "x.pem" {
+		t.Errorf("Expected backend x with cert x.pem.\nBut got: %+v\n", config.Backends)
+	}
+	if config.Port != 80 || len(config.Upstreams) != 0 {
+		t.Errorf("Expected port 80 and no upstreams.\nBut got: %d and %+v\n", config.Port, config.Upstreams)
+	}
+}
+
+// TestIniSource_WriteCurrent tests that the current values are written
+// in sorted order and can be loaded again.
+func TestIniSource_WriteCurrent(t *testing.T) {
+	config := &struct {
+		Password  string              `name:"password" secret:"true" usage:"The password"`
+		Port      int                 `name:"port"`
+		Upstreams map[string]upstream `name:"upstream"`
+	}{Password: "default", Port: 1}
+	content := "password = s3cr3t\n" +
+		"[upstream \"b\"]\nhost = b\n" +
+		"[upstream \"a\"]\nhost = a\n[upstream \"a\".tls]\ncert = a.pem\n"
+	src := FromBytes([]byte(content))
+	cfg := congo.New("test", src).Using(config)
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := src.WriteCurrent(out, true); err != nil {
+		t.Fatalf("Expected to write without problems.\nBut got error: %s\n", err)
+	}
+	expected := "" +
+		"; The password\n" +
+		"password = <redacted>\n" +
+		"port     = 1\n\n" +
+		"[upstream \"b\"]\n" +
+		"host = b\n" +
+		"port = 0\n\n" +
+		"[upstream \"b\".tls]\n" +
+		"cert = \n\n" +
+		"[upstream \"a\"]\n" +
+		"host = a\n" +
+		"port = 0\n\n" +
+		"[upstream \"a\".tls]\n" +
+		"cert = a.pem"
+	if actual := strings.TrimSpace(out.String()); actual != expected {
+		t.Errorf("Expected written values to be:\n%s\nBut was:\n%s\n", expected, actual)
+	}
+
+	out.Reset()
+	src.WriteCurrent(out, false)
+	copied := &struct {
+		Password  string              `name:"password" secret:"true" usage:"The password"`
+		Port      int                 `name:"port"`
+		Upstreams map[string]upstream `name:"upstream"`
+	}{}
+	cfg = congo.New("copy", FromBytes(out.Bytes())).Using(copied)
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load the written values without problems.\nBut got error: %s\n", err)
+	}
+	if !reflect.DeepEqual(copied, config) {
+		t.Errorf("Expected the written values to load into %+v.\nBut got: %+v\n", config, copied)
+	}
+}
@@ -0,0 +1,46 @@
+package json
+
+import (
+	"fmt"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Example is a basic example for the usage of the json source.
+func Example() {
+	// Get json source
+	src := FromBytes([]byte(`{
+		"number": 54,
+		"hosts": ["alpha", "beta"],
+		"server": {"timeout": "2h45m"}
+	}`))
+
+	type Server struct {
+		Timeout string `name:"timeout"`
+	}
+	type Configuration struct {
+		Number int      `name:"number"`
+		Hosts  []string `name:"hosts"`
+		Server Server   `name:"server"`
+	}
+	config := Configuration{}
+	cfg := congo.New("main", src).Using(&config)
+
+	// Load configuration
+	cfg.Init()
+	cfg.Load()
+
+	fmt.Printf("Using number %d with hosts %v\n", config.Number, config.Hosts)
+	fmt.Println(config.Server.Timeout)
+	//Output:
+	//Using number 54 with hosts [alpha beta]
+	//2h45m
+}
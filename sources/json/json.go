@@ -0,0 +1,311 @@
+// Package json provides a source loading settings from JSON documents.
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/internal/input"
+	"gitlab.com/silentteacup/congo/internal/tree"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// New creates a new json source which reads from
+// given reader.
+func New(reader io.Reader) Source {
+	return createSource(reader)
+}
+
+// FromBytes creates a new json source directly from
+// the data that should be read.
+func FromBytes(content []byte) Source {
+	return createSource(content)
+}
+
+// FromFile creates a new json source which uses the
+// file at given path to load the configuration.
+func FromFile(path string) Source {
+	return createSource(path)
+}
+
+// createSource creates the json source with default values
+// using given source as source for the json-document.
+func createSource(source interface{}) Source {
+	return &jsonSource{input.New(source), "", true, nil}
+}
+
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "json"
+
+// CommentPrefix starts keys that are ignored when loading. WriteDefaults
+// uses them to describe settings, since JSON has no comments.
+const CommentPrefix = "//"
+
+// Source a json source uses a JSON document to load settings.
+//
+// Nested objects contain the settings with hierarchical names
+// (e.g. "database.pool.max-size") and arrays the elements of settings
+// holding several elements.
+type Source interface {
+	congo.Source
+	Path(path string) Source
+	WriteDefaults(w io.Writer) error
+	SetLooseLoad(loose bool) Source
+}
+
+type jsonSource struct {
+	input     *input.Input
+	path      string
+	looseLoad bool
+	defaults  map[string]*congo.Setting
+}
+
+// Init initializes the json source.
+func (s *jsonSource) Init(settings map[string]*congo.Setting) error {
+	s.defaults = settings
+	return nil
+}
+
+// Names returns the path of the value that sets the setting with given name.
+func (s *jsonSource) Names(setting string) []string {
+	return []string{s.locate(setting)}
+}
+
+// locate returns the path of the setting with given name in the document.
+func (s *jsonSource) locate(name string) string {
+	if s.path == "" {
+		return name
+	}
+	return s.path + congo.NameSeparator + name
+}
+
+// Load loads the settings from the JSON document.
+// All settings that can't be set are reported as congo.Errors.
+func (s *jsonSource) Load(settings map[string]*congo.Setting) error {
+	data, err := s.input.Content(s.looseLoad)
+	if err != nil {
+		return fmt.Errorf("json-source: couldn't load the json-file because: %w", err)
+	}
+	root, err := parse(s.input.Name(), data)
+	if err != nil {
+		return fmt.Errorf("json-source: couldn't load the json-file because: %w", err)
+	}
+	node := root.Lookup(s.path)
+	if node == nil {
+		// The object doesn't exist.
+		// We simply don't load any setting and use the defaults.
+		return nil
+	}
+	return tree.Apply(node, sourceName, settings)
+}
+
+// WriteDefaults writes the default settings to given writer.
+// The help of every setting is written as value of a key named like the
+// setting preceded by CommentPrefix.
+// If an error occurs nothing will be written.
+func (s *jsonSource) WriteDefaults(w io.Writer) error {
+	template, err := tree.NewTemplate(s.defaults, s.path)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	writeTemplate(&buffer, template, "")
+	buffer.WriteString("\n")
+	_, err = buffer.WriteTo(w)
+	return err
+}
+
+// writeTemplate writes the settings of given template as JSON object.
+func writeTemplate(buffer *bytes.Buffer, t *tree.Template, indent string) {
+	const step = "  "
+	var members []string
+	for _, key := range t.Keys {
+		prefix := indent + step + quote(key) + ": "
+		if group, ok := t.Groups[key]; ok {
+			var nested bytes.Buffer
+			writeTemplate(&nested, group, indent+step)
+			members = append(members, prefix+nested.String())
+			continue
+		}
+		setting := t.Settings[key]
+		if help := setting.Help(); help != "" {
+			members = append(members, indent+step+quote(CommentPrefix+key)+": "+quote(help))
+		}
+		members = append(members, prefix+literal(setting))
+	}
+	if len(members) == 0 {
+		buffer.WriteString("{}")
+		return
+	}
+	buffer.WriteString("{\n")
+	buffer.WriteString(strings.Join(members, ",\n"))
+	buffer.WriteString("\n" + indent + "}")
+}
+
+// literal returns the default value of the setting as JSON value.
+// Booleans and numbers are written as such, everything else as string.
+func literal(setting *congo.Setting) string {
+	if tree.KindOf(setting.Value) != tree.Text && json.Valid([]byte(setting.DefValue)) {
+		return setting.DefValue
+	}
+	return quote(setting.DefValue)
+}
+
+// quote returns s as JSON string.
+func quote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// SetLooseLoad sets whether this source should complain if the file
+// doesn't exist. Default is true.
+func (s *jsonSource) SetLooseLoad(loose bool) Source {
+	s.looseLoad = loose
+	return s
+}
+
+// Path creates a sub-source that loads settings from a nested object
+// of the JSON document. The path names the object with its keys
+// separated by congo.NameSeparator e.g. "services.api".
+func (s *jsonSource) Path(path string) Source {
+	return &jsonSource{
+		s.input,
+		s.locate(path),
+		s.looseLoad,
+		s.defaults,
+	}
+}
+
+// parser builds the document of JSON data.
+type parser struct {
+	name    string
+	data    []byte
+	decoder *json.Decoder
+}
+
+// parse parses the JSON object in data. Empty data is an empty object.
+func parse(name string, data []byte) (*tree.Node, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return tree.NewObject(name), nil
+	}
+	p := &parser{name, data, json.NewDecoder(bytes.NewReader(data))}
+	// Numbers are kept as written, so they aren't changed by a conversion to float64.
+	p.decoder.UseNumber()
+	token, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("%s: expected an object", p.location())
+	}
+	root, err := p.object()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%s: unexpected data after the object", p.location())
+	}
+	return root, nil
+}
+
+// location returns the location of the token read last.
+func (p *parser) location() string {
+	offset := p.decoder.InputOffset()
+	return fmt.Sprintf("%s:%d", p.name, 1+bytes.Count(p.data[:offset], []byte("\n")))
+}
+
+// token reads the next token. Errors describe their location.
+func (p *parser) token() (json.Token, error) {
+	token, err := p.decoder.Token()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s: unexpected end of input", p.location())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", p.location(), err)
+	}
+	return token, nil
+}
+
+// object reads the members of an object after its opening brace.
+// Keys starting with CommentPrefix are skipped.
+func (p *parser) object() (*tree.Node, error) {
+	node := tree.NewObject(p.location())
+	for p.decoder.More() {
+		token, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		if token, err = p.token(); err != nil {
+			return nil, err
+		}
+		child, err := p.value(token)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(key, CommentPrefix) {
+			node.Children[key] = child
+		}
+	}
+	_, err := p.token() // closing brace
+	return node, err
+}
+
+// array reads the elements of an array after its opening bracket.
+// Null elements are skipped.
+func (p *parser) array() (*tree.Node, error) {
+	node := &tree.Node{List: true, Location: p.location()}
+	for p.decoder.More() {
+		token, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		element, err := p.value(token)
+		if err != nil {
+			return nil, err
+		}
+		if element.Children != nil || element.List {
+			return nil, fmt.Errorf("%s: nested objects and arrays in arrays are not supported", element.Location)
+		}
+		node.Values = append(node.Values, element.Values...)
+	}
+	_, err := p.token() // closing bracket
+	return node, err
+}
+
+// value reads the value starting with given token.
+func (p *parser) value(token json.Token) (*tree.Node, error) {
+	location := p.location()
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '{' {
+			return p.object()
+		}
+		return p.array()
+	case nil:
+		return &tree.Node{Location: location}, nil
+	case bool:
+		return scalar(strconv.FormatBool(t), location), nil
+	case json.Number:
+		return scalar(t.String(), location), nil
+	default:
+		return scalar(fmt.Sprint(t), location), nil
+	}
+}
+
+// scalar creates a node holding a single value.
+func scalar(raw string, location string) *tree.Node {
+	return &tree.Node{Values: []tree.Scalar{{Raw: raw, Location: location}}, Location: location}
+}
@@ -0,0 +1,188 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+const content = `{
+  "debug": true,
+  "big": 18446744073709551615,
+  "ratio": 0.1,
+  "tags": ["a", "b"],
+  "labels": {"team": "core", "tier": "1"},
+  "nothing": null,
+  "//debug": "a comment",
+  "database": {
+    "pool": {
+      "max-size": 10,
+      "timeouts": ["1s", "2s"]
+    }
+  }
+}`
+
+func TestJSONSource_Load(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(content)))
+	debug := cfg.Bool("debug", false, "")
+	big := cfg.Uint64("big", 0, "")
+	ratio := cfg.Float64("ratio", 0, "")
+	tags := cfg.StringSlice("tags", []string{"default"}, "")
+	labels := cfg.StringMap("labels", nil, "")
+	nothing := cfg.String("nothing", "default", "")
+	maxSize := cfg.Int("database.pool.max-size", 0, "")
+	timeouts := cfg.DurationSlice("database.pool.timeouts", nil, "")
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if !*debug || *big != 18446744073709551615 || *ratio != 0.1 || *maxSize != 10 {
+		t.Errorf("Expected scalars to be loaded.\nBut got: %v %v %v %v\n", *debug, *big, *ratio, *maxSize)
+	}
+	if len(*tags) != 2 || (*tags)[0] != "a" || (*tags)[1] != "b" {
+		t.Errorf("Expected array to be loaded into slice.\nBut got: %v\n", *tags)
+	}
+	if len(*labels) != 2 || (*labels)["team"] != "core" || (*labels)["tier"] != "1" {
+		t.Errorf("Expected object to be loaded into map.\nBut got: %v\n", *labels)
+	}
+	if *nothing != "default" {
+		t.Errorf("Expected null to be ignored.\nBut got: %q\n", *nothing)
+	}
+	if len(*timeouts) != 2 || (*timeouts)[1] != 2*time.Second {
+		t.Errorf("Expected nested array to be loaded.\nBut got: %v\n", *timeouts)
+	}
+	expected := congo.Origin{Source: "json", Raw: "10", Location: "<bytes>:11"}
+	if origin, _ := cfg.Origin("database.pool.max-size"); origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+}
+
+func TestJSONSource_Path(t *testing.T) {
+	src := FromBytes([]byte(content))
+	cfg := congo.New("test", src.Path("database").Path("pool"))
+	maxSize := cfg.Int("max-size", 0, "")
+	missing := congo.New("missing", src.Path("missing"))
+	missing.Int("max-size", 0, "")
+	cfg.Init()
+	missing.Init()
+
+	if err := cfg.Load(); err != nil || *maxSize != 10 {
+		t.Errorf("Expected setting to be loaded from path.\nBut got: %d (error: %v)\n", *maxSize, err)
+	}
+	if err := missing.Load(); err != nil {
+		t.Errorf("Expected missing path to be ignored.\nBut got error: %s\n", err)
+	}
+}
+
+func TestJSONSource_Load_Errors(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(content)))
+	cfg.Int("ratio", 0, "")
+	cfg.Int("database", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var errs congo.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected both settings to be reported.\nBut got: %v\n", err)
+	}
+	var loadErr *congo.LoadError
+	if !errors.As(errs[1], &loadErr) || loadErr.Setting != "ratio" || loadErr.Location != "<bytes>:4" {
+		t.Errorf("Expected error to describe setting %q.\nBut got: %v\n", "ratio", errs[1])
+	}
+}
+
+func TestJSONSource_Load_Invalid(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"{\n  \"a\": 1,\n  \"b\": }", "<bytes>:3"},
+		{"[1, 2]", "expected an object"},
+		{"{\"a\": [[1]]}", "nested objects and arrays"},
+		{"{} {}", "unexpected data"},
+		{"{\"a\": 1", "unexpected end"},
+	}
+	for _, test := range tests {
+		err := FromBytes([]byte(test.content)).Load(map[string]*congo.Setting{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q for %q.\nBut got: %v\n", test.expected, test.content, err)
+		}
+	}
+}
+
+func TestJSONSource_Load_NotLoose(t *testing.T) {
+	settings := map[string]*congo.Setting{}
+	if err := FromFile("").Load(settings); err != nil {
+		t.Errorf("Expected load with non-existent file to work without errors.\nBut got error: %s\n", err)
+	}
+	if err := FromFile("").SetLooseLoad(false).Load(settings); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected load with non-existent file to fail with os.ErrNotExist.\nBut got: %v\n", err)
+	}
+}
+
+func TestJSONSource_WriteDefaults(t *testing.T) {
+	src := FromBytes(nil).Path("app")
+	cfg := congo.New("test", src)
+	cfg.Int("port", 80, "Port to listen on", congo.Min("1"))
+	cfg.Bool("debug", false, "")
+	cfg.Duration("database.timeout", time.Second, "")
+	cfg.StringSlice("tags", []string{"a", "b"}, "")
+	cfg.Init()
+
+	var buffer bytes.Buffer
+	if err := src.WriteDefaults(&buffer); err != nil {
+		t.Fatalf("Expected to write without problems.\nBut got error: %s\n", err)
+	}
+	expected := `{
+  "app": {
+    "database": {
+      "timeout": "1s"
+    },
+    "debug": false,
+    "//port": "Port to listen on (min=1)",
+    "port": 80,
+    "tags": "a,b"
+  }
+}
+`
+	if buffer.String() != expected {
+		t.Errorf("Expected defaults:\n%s\nBut got:\n%s\n", expected, buffer.String())
+	}
+
+	// The defaults can be loaded again.
+	if err := FromBytes(buffer.Bytes()).Path("app").Load(map[string]*congo.Setting{}); err != nil {
+		t.Errorf("Expected defaults to be loadable.\nBut got error: %s\n", err)
+	}
+}
+
+func TestJSONSource_Load_Groups(t *testing.T) {
+	config := &struct {
+		Upstreams map[string]struct {
+			Host string `name:"host"`
+			Port int    `name:"port"`
+		} `name:"upstreams"`
+	}{}
+	content := `{"upstreams": {"a": {"host": "a.example.com", "port": 80}, "b": {"host": "b.example.com"}}}`
+	cfg := congo.New("test", FromBytes([]byte(content))).Using(config)
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if len(config.Upstreams) != 2 || config.Upstreams["a"].Port != 80 || config.Upstreams["b"].Host != "b.example.com" {
+		t.Errorf("Expected objects to be loaded into groups.\nBut got: %+v\n", config.Upstreams)
+	}
+}
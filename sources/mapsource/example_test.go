@@ -0,0 +1,42 @@
+package mapsource
+
+import (
+	"fmt"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Example is a basic example for the usage of the map source.
+func Example() {
+	overrides := New(map[string]string{
+		"number": "54",
+	})
+
+	// Configuration
+	cfg := congo.New("main", overrides)
+
+	debug := cfg.Bool("debug", false, "Can be used to enable debug mode.")
+	number := cfg.Int("number", 0, "Set a number")
+
+	// Load configurations
+	cfg.Init()
+	cfg.Load()
+	fmt.Printf("Using number %d with debug %t\n", *number, *debug)
+
+	// Override a setting at runtime
+	overrides.Set("debug", "true")
+	cfg.Reload()
+	fmt.Printf("Using number %d with debug %t\n", *number, *debug)
+
+	//Output:
+	//Using number 54 with debug false
+	//Using number 54 with debug true
+}
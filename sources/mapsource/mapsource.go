@@ -0,0 +1,223 @@
+// Package mapsource provides a source loading settings from maps held in
+// memory. It is meant for tests and for overriding settings programmatically.
+package mapsource
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/internal/tree"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "map"
+
+// New creates a new map source which loads settings from given values by
+// the names of the settings. The map is copied, later changes have to be
+// made with Set.
+func New(values map[string]string) Source {
+	s := &source{values: make(map[string]interface{}, len(values))}
+	for name, value := range values {
+		s.values[name] = value
+	}
+	return s
+}
+
+// FromValues creates a new map source which loads settings from given values
+// by the names of the settings. The map is copied, later changes have to be
+// made with SetValue.
+//
+// Strings and byte slices are given to the settings as they are, values
+// implementing encoding.TextMarshaler in their text form and all other
+// scalars in their default format (see fmt.Sprint). Slices and arrays set the elements of
+// settings holding several elements, maps with string keys either hold
+// settings with hierarchical names or the entries of settings holding maps,
+// just like objects in JSON documents. Nil values are ignored.
+func FromValues(values map[string]interface{}) Source {
+	s := &source{values: make(map[string]interface{}, len(values))}
+	for name, value := range values {
+		s.values[name] = value
+	}
+	return s
+}
+
+// Source is a source that loads settings from a map. It is safe to update
+// the values while the configuration is loaded.
+type Source interface {
+	congo.Source
+	// Set sets the value for the setting with given name.
+	Set(name string, value string)
+	// SetValue sets the value for the setting with given name.
+	// See FromValues for how values are given to the settings.
+	SetValue(name string, value interface{})
+	// Delete removes the value for the setting with given name.
+	Delete(name string)
+}
+
+type source struct {
+	mutex  sync.Mutex
+	values map[string]interface{}
+}
+
+// Set sets the value for the setting with given name.
+func (s *source) Set(name string, value string) {
+	s.SetValue(name, value)
+}
+
+// SetValue sets the value for the setting with given name.
+// See FromValues for how values are given to the settings.
+func (s *source) SetValue(name string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.values[name] = value
+}
+
+// Delete removes the value for the setting with given name.
+func (s *source) Delete(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.values, name)
+}
+
+// Init initializes this source
+func (s *source) Init(map[string]*congo.Setting) error {
+	// Do nothing
+	return nil
+}
+
+// Load loads the settings from the values of this source.
+// All settings that can't be set are reported as congo.Errors.
+func (s *source) Load(settings map[string]*congo.Setting) error {
+	root, err := s.document()
+	if err != nil {
+		return fmt.Errorf("map-source: couldn't load the values because: %s", err)
+	}
+	return tree.Apply(root, sourceName, settings)
+}
+
+// document returns the current values as document.
+func (s *source) document() (*tree.Node, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	root := tree.NewObject("")
+	for _, name := range names {
+		node, err := convert(reflect.ValueOf(s.values[name]), true)
+		if err != nil {
+			return nil, fmt.Errorf("value of %q: %s", name, err)
+		}
+		if err := insert(root, strings.Split(name, congo.NameSeparator), node); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// convert converts given value into a node. Objects and arrays are only
+// allowed if nested is set.
+func convert(v reflect.Value, nested bool) (*tree.Node, error) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch {
+	case !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil():
+		return &tree.Node{}, nil
+	case v.Type().Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return &tree.Node{Values: []tree.Scalar{{Raw: string(text)}}}, nil
+	case v.Kind() == reflect.String:
+		return &tree.Node{Values: []tree.Scalar{{Raw: v.String()}}}, nil
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return &tree.Node{Values: []tree.Scalar{{Raw: string(b)}}}, nil
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if !nested {
+			return nil, errNested
+		}
+		node := tree.NewObject("")
+		for _, key := range v.MapKeys() {
+			child, err := convert(v.MapIndex(key), true)
+			if err != nil {
+				return nil, err
+			}
+			node.Children[key.String()] = child
+		}
+		return node, nil
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if !nested {
+			return nil, errNested
+		}
+		node := &tree.Node{List: true}
+		for i := 0; i < v.Len(); i++ {
+			element, err := convert(v.Index(i), false)
+			if err != nil {
+				return nil, err
+			}
+			node.Values = append(node.Values, element.Values...)
+		}
+		return node, nil
+	}
+	return &tree.Node{Values: []tree.Scalar{{Raw: fmt.Sprint(v.Interface())}}}, nil
+}
+
+var errNested = errors.New("nested objects and arrays are not supported")
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// insert inserts given node at given path into root. Objects given for
+// the same path are merged.
+func insert(root *tree.Node, path []string, node *tree.Node) error {
+	parent := root
+	for i, key := range path[:len(path)-1] {
+		child, ok := parent.Children[key]
+		if !ok {
+			child = tree.NewObject("")
+			parent.Children[key] = child
+		} else if child.Children == nil {
+			return fmt.Errorf("%q is a value and can't hold other values", strings.Join(path[:i+1], congo.NameSeparator))
+		}
+		parent = child
+	}
+	key := path[len(path)-1]
+	existing, ok := parent.Children[key]
+	if !ok {
+		parent.Children[key] = node
+		return nil
+	}
+	if existing.Children == nil || node.Children == nil {
+		return fmt.Errorf("%q is given more than once", strings.Join(path, congo.NameSeparator))
+	}
+	keys := make([]string, 0, len(node.Children))
+	for child := range node.Children {
+		keys = append(keys, child)
+	}
+	sort.Strings(keys)
+	for _, child := range keys {
+		if err := insert(root, append(path[:len(path):len(path)], child), node.Children[child]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package mapsource
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+func TestSource_Load(t *testing.T) {
+	src := New(map[string]string{
+		"name":          "congo",
+		"database.port": "5432",
+	})
+	cfg := congo.New("test", src)
+	name := cfg.String("name", "", "")
+	port := cfg.Int("database.port", 0, "")
+	debug := cfg.Bool("debug", false, "")
+	if err := cfg.Init(); err != nil {
+		t.Fatalf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *name != "congo" || *port != 5432 || *debug {
+		t.Errorf("Expected name congo, port 5432 and debug false.\nBut got: %q, %d and %t\n", *name, *port, *debug)
+	}
+	origin := congo.Origin{Source: "map", Raw: "congo"}
+	if got, _ := cfg.Origin("name"); got != origin {
+		t.Errorf("Expected origin %+v.\nBut got: %+v\n", origin, got)
+	}
+
+	src.Set("debug", "true")
+	src.Delete("name")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Expected to reload without problems.\nBut got error: %s\n", err)
+	}
+	if *name != "" || !*debug {
+		t.Errorf("Expected name to be reset and debug to be enabled.\nBut got: %q and %t\n", *name, *debug)
+	}
+}
+
+func TestSource_LoadErrors(t *testing.T) {
+	cfg := congo.New("test", New(map[string]string{"port": "eighty"}))
+	cfg.Int("port", 0, "")
+	cfg.Init()
+	err := cfg.Load()
+	var loadErr *congo.LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("Expected a *congo.LoadError.\nBut got: %v\n", err)
+	}
+	if loadErr.Setting != "port" || loadErr.Raw != "eighty" || loadErr.Source != "map" {
+		t.Errorf("Expected error for setting port from value eighty.\nBut got: %+v\n", *loadErr)
+	}
+}
+
+func TestFromValues(t *testing.T) {
+	src := FromValues(map[string]interface{}{
+		"timeout": 3 * time.Second,
+		"ratio":   0.5,
+		"name":    []byte("congo"),
+		"ip":      net.IPv4(127, 0, 0, 1),
+		"tags":    []string{"a", "b"},
+		"labels":  map[string]interface{}{"env": "prod"},
+		"database": map[string]interface{}{
+			"port": 5432,
+			"host": nil,
+		},
+	})
+	cfg := congo.New("test", src)
+	timeout := cfg.Duration("timeout", 0, "")
+	ratio := cfg.Float64("ratio", 0, "")
+	name := cfg.String("name", "", "")
+	ip := cfg.String("ip", "", "")
+	tags := cfg.StringSlice("tags", nil, "")
+	labels := cfg.StringMap("labels", nil, "")
+	port := cfg.Int("database.port", 0, "")
+	host := cfg.String("database.host", "localhost", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if *timeout != 3*time.Second || *ratio != 0.5 || *port != 5432 || *host != "localhost" {
+		t.Errorf("Expected timeout 3s, ratio 0.5, port 5432 and host localhost.\nBut got: %s, %f, %d and %s\n",
+			*timeout, *ratio, *port, *host)
+	}
+	if *name != "congo" || *ip != "127.0.0.1" {
+		t.Errorf("Expected name congo and ip 127.0.0.1.\nBut got: %s and %s\n", *name, *ip)
+	}
+	if !reflect.DeepEqual(*tags, []string{"a", "b"}) {
+		t.Errorf("Expected tags [a b].\nBut got: %v\n", *tags)
+	}
+	if !reflect.DeepEqual(*labels, map[string]string{"env": "prod"}) {
+		t.Errorf("Expected labels map[env:prod].\nBut got: %v\n", *labels)
+	}
+}
+
+func TestFromValues_Conflicts(t *testing.T) {
+	tests := []struct {
+		values   map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"a": "1", "a.b": "2"}, `"a" is a value`},
+		{map[string]interface{}{"a.b": "1", "a": map[string]interface{}{"b": "2"}}, `"a.b" is given more than once`},
+		{map[string]interface{}{"a": []interface{}{[]string{"x"}}}, "nested objects"},
+	}
+	for _, test := range tests {
+		cfg := congo.New("test", FromValues(test.values))
+		cfg.String("a", "", "")
+		cfg.Init()
+		if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q.\nBut got: %v\n", test.expected, err)
+		}
+	}
+}
+
+func TestSource_Concurrent(t *testing.T) {
+	src := New(nil)
+	cfg := congo.New("test", src)
+	cfg.Int("number", 0, "")
+	cfg.Init()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			src.SetValue("number", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if err := cfg.Reload(); err != nil {
+				t.Errorf("Expected to reload without problems.\nBut got error: %s\n", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
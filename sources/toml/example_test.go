@@ -0,0 +1,48 @@
+package toml
+
+import (
+	"fmt"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// example is the content of a toml-file used in
+// this example
+const example = `
+# Number
+number = 54
+hosts = ["alpha", "beta"]
+
+[server]
+timeout = "2h45m"
+`
+
+// Example is a basic example for the usage of the toml source.
+func Example() {
+	// Get toml source
+	src := FromBytes([]byte(example))
+
+	// main configuration
+	cfg := congo.New("main", src)
+	number := cfg.Int("number", 0, "Set a number")
+	hosts := cfg.StringSlice("hosts", nil, "Hosts to connect to")
+	timeout := cfg.Duration("server.timeout", 0, "Set the timeout.")
+
+	// Load configuration
+	cfg.Init()
+	cfg.Load()
+
+	fmt.Printf("Using number %d with hosts %v\n", *number, *hosts)
+	fmt.Println(*timeout)
+	//Output:
+	//Using number 54 with hosts [alpha beta]
+	//2h45m0s
+}
@@ -0,0 +1,471 @@
+package toml
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gitlab.com/silentteacup/congo/internal/tree"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+var (
+	decimalInt  = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	prefixedInt = regexp.MustCompile(`^0(x[0-9A-Fa-f](_?[0-9A-Fa-f])*|o[0-7](_?[0-7])*|b[01](_?[01])*)$`)
+	float       = regexp.MustCompile(`^[+-]?((0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?|inf|nan)$`)
+	dateTime    = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}` +
+		`([Tt ][0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?([Zz]|[+-][0-9]{2}:[0-9]{2})?)?$`)
+	localTime = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`)
+	bareKey   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// parser builds the document of TOML data.
+type parser struct {
+	name    string
+	data    string
+	pos     int                 // offset of the next character
+	root    *tree.Node          // root table
+	table   *tree.Node          // table key/value pairs are added to
+	defined map[*tree.Node]bool // tables defined by a header
+}
+
+// parse parses the TOML document in data.
+func parse(name string, data []byte) (*tree.Node, error) {
+	root := tree.NewObject(name)
+	p := &parser{name: name, data: string(data), root: root, table: root, defined: make(map[*tree.Node]bool)}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		if p.peek() == '[' {
+			err = p.header()
+		} else {
+			err = p.keyValue(p.table)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// location returns the location of given offset.
+func (p *parser) location(pos int) string {
+	return fmt.Sprintf("%s:%d", p.name, 1+strings.Count(p.data[:pos], "\n"))
+}
+
+// errorf returns an error describing a problem at the current offset.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", p.location(p.pos), fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// consume reads s if the data continues with it.
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.data[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// skipSpace skips spaces and tabs.
+func (p *parser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to the end of the line.
+func (p *parser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank skips whitespace, comments and line breaks.
+func (p *parser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		if !p.consume("\n") && !p.consume("\r\n") {
+			return
+		}
+	}
+}
+
+// endOfLine reads the rest of a line which may only contain a comment.
+func (p *parser) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.eof() || p.consume("\n") || p.consume("\r\n") {
+		return nil
+	}
+	return p.errorf("expected the end of the line but got %q", p.peek())
+}
+
+// header reads a table header and makes the table the current one.
+func (p *parser) header() error {
+	if p.consume("[[") {
+		return p.errorf("arrays of tables are not supported")
+	}
+	p.pos++
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !p.consume("]") {
+		return p.errorf("expected ']' after table name")
+	}
+	table := p.root
+	for _, key := range keys {
+		child := table.Children[key]
+		if child == nil {
+			child = tree.NewObject(p.location(p.pos))
+			table.Children[key] = child
+		} else if child.Children == nil {
+			return p.errorf("key %q is already defined as value", key)
+		}
+		table = child
+	}
+	if p.defined[table] {
+		return p.errorf("table %q is defined twice", strings.Join(keys, "."))
+	}
+	p.defined[table] = true
+	p.table = table
+	return nil
+}
+
+// keyValue reads a key/value pair and adds it to given table. Dotted keys
+// define the tables leading to the value.
+func (p *parser) keyValue(table *tree.Node) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !p.consume("=") {
+		return p.errorf("expected '=' after key")
+	}
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys[:len(keys)-1] {
+		child := table.Children[key]
+		if child == nil {
+			child = tree.NewObject(value.Location)
+			table.Children[key] = child
+		} else if child.Children == nil {
+			return p.errorf("key %q is already defined as value", key)
+		}
+		table = child
+	}
+	key := keys[len(keys)-1]
+	if _, ok := table.Children[key]; ok {
+		return p.errorf("duplicate key %q", key)
+	}
+	table.Children[key] = value
+	return nil
+}
+
+// key reads a possibly dotted key.
+func (p *parser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		switch p.peek() {
+		case '"':
+			p.pos++
+			var err error
+			if key, err = p.basicString(); err != nil {
+				return nil, err
+			}
+		case '\'':
+			p.pos++
+			var err error
+			if key, err = p.literalString(); err != nil {
+				return nil, err
+			}
+		default:
+			start := p.pos
+			for !p.eof() && bareKey.MatchString(p.data[p.pos:p.pos+1]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			key = p.data[start:p.pos]
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if !p.consume(".") {
+			return keys, nil
+		}
+	}
+}
+
+// value reads a value. Values are converted to the string form Value.Set
+// expects without losing precision: integers are written in decimal,
+// floats and datetimes as given.
+func (p *parser) value() (*tree.Node, error) {
+	location := p.location(p.pos)
+	switch {
+	case p.consume(`"""`):
+		s, err := p.multiLineString(`"""`, true)
+		return scalar(s, location), err
+	case p.consume(`"`):
+		s, err := p.basicString()
+		return scalar(s, location), err
+	case p.consume("'''"):
+		s, err := p.multiLineString("'''", false)
+		return scalar(s, location), err
+	case p.consume("'"):
+		s, err := p.literalString()
+		return scalar(s, location), err
+	case p.consume("["):
+		return p.array(location)
+	case p.consume("{"):
+		return p.inlineTable(location)
+	}
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	raw := p.data[start:p.pos]
+	// Datetimes may separate date and time by a space.
+	if len(raw) == 10 && dateTime.MatchString(raw) && strings.HasPrefix(p.data[p.pos:], " ") &&
+		len(p.data) > p.pos+3 && isDigit(p.data[p.pos+1]) && isDigit(p.data[p.pos+2]) && p.data[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+			p.pos++
+		}
+		raw = p.data[start:p.pos]
+	}
+	s, err := convert(raw)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("%s", err)
+	}
+	return scalar(s, location), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// convert converts a bare value (boolean, number or datetime).
+func convert(raw string) (string, error) {
+	switch {
+	case raw == "true" || raw == "false":
+		return raw, nil
+	case decimalInt.MatchString(raw):
+		return strings.TrimPrefix(strings.Replace(raw, "_", "", -1), "+"), nil
+	case prefixedInt.MatchString(raw):
+		n, err := strconv.ParseInt(strings.Replace(raw, "_", "", -1), 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid integer %q", raw)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case float.MatchString(raw):
+		return strings.Replace(raw, "_", "", -1), nil
+	case dateTime.MatchString(raw):
+		// RFC 3339 requires upper case separators.
+		if len(raw) > 10 {
+			raw = raw[:10] + "T" + strings.ToUpper(raw[11:])
+		}
+		return raw, nil
+	case localTime.MatchString(raw):
+		return raw, nil
+	case raw == "":
+		return "", fmt.Errorf("expected a value")
+	default:
+		return "", fmt.Errorf("invalid value %q", raw)
+	}
+}
+
+// array reads the elements of an array after its opening bracket.
+func (p *parser) array(location string) (*tree.Node, error) {
+	node := &tree.Node{List: true, Location: location}
+	for {
+		p.skipBlank()
+		if p.consume("]") {
+			return node, nil
+		}
+		element, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if element.Children != nil || element.List {
+			return nil, p.errorf("nested arrays and tables in arrays are not supported")
+		}
+		node.Values = append(node.Values, element.Values...)
+		p.skipBlank()
+		if !p.consume(",") && p.peek() != ']' {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// inlineTable reads the key/value pairs of an inline table after its
+// opening brace.
+func (p *parser) inlineTable(location string) (*tree.Node, error) {
+	node := tree.NewObject(location)
+	p.skipSpace()
+	if p.consume("}") {
+		return node, nil
+	}
+	for {
+		if err := p.keyValue(node); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.consume("}") {
+			return node, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// literalString reads a literal string after its opening quote.
+func (p *parser) literalString() (string, error) {
+	end := strings.IndexAny(p.data[p.pos:], "'\n")
+	if end < 0 || p.data[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.data[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// basicString reads a basic string after its opening quote.
+func (p *parser) basicString() (string, error) {
+	var b strings.Builder
+	for {
+		switch {
+		case p.eof() || p.peek() == '\n':
+			return "", p.errorf("unterminated string")
+		case p.consume(`"`):
+			return b.String(), nil
+		case p.peek() == '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(p.data[p.pos])
+			p.pos++
+		}
+	}
+}
+
+// multiLineString reads a multi-line string after its opening delimiter.
+// A line break directly following the delimiter is trimmed. Basic strings
+// support escapes and line ending backslashes, which trim the following
+// whitespace.
+func (p *parser) multiLineString(delimiter string, basic bool) (string, error) {
+	if !p.consume("\n") {
+		p.consume("\r\n")
+	}
+	var b strings.Builder
+	for {
+		switch {
+		case p.eof():
+			return "", p.errorf("unterminated string")
+		case p.consume(delimiter):
+			// Up to two quotes may directly precede the closing delimiter.
+			for i := 0; i < 2 && p.peek() == delimiter[0]; i++ {
+				b.WriteByte(delimiter[0])
+				p.pos++
+			}
+			return b.String(), nil
+		case basic && p.peek() == '\\':
+			rest := strings.TrimLeft(p.data[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.pos = len(p.data) - len(strings.TrimLeft(rest, " \t\r\n"))
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		case p.consume("\r\n"):
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(p.data[p.pos])
+			p.pos++
+		}
+	}
+}
+
+// escape reads an escape sequence of a basic string.
+func (p *parser) escape(b *strings.Builder) error {
+	p.pos++
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.data) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.data[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+		p.pos += n
+	default:
+		p.pos -= 2
+		return p.errorf("invalid escape sequence")
+	}
+	return nil
+}
+
+// scalar creates a node holding a single value.
+func scalar(raw string, location string) *tree.Node {
+	return &tree.Node{Values: []tree.Scalar{{Raw: raw, Location: location}}, Location: location}
+}
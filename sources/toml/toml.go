@@ -0,0 +1,232 @@
+// Package toml provides a source loading settings from TOML documents.
+//
+// Arrays of tables aren't supported.
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/internal/input"
+	"gitlab.com/silentteacup/congo/internal/tree"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// New creates a new toml source which reads from
+// given reader.
+func New(reader io.Reader) Source {
+	return createSource(reader)
+}
+
+// FromBytes creates a new toml source directly from
+// the data that should be read.
+func FromBytes(content []byte) Source {
+	return createSource(content)
+}
+
+// FromFile creates a new toml source which uses the
+// file at given path to load the configuration.
+func FromFile(path string) Source {
+	return createSource(path)
+}
+
+// createSource creates the toml source with default values
+// using given source as source for the toml-document.
+func createSource(source interface{}) Source {
+	return &tomlSource{input.New(source), "", true, nil}
+}
+
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "toml"
+
+// Source a toml source uses a TOML document to load settings.
+//
+// Tables contain the settings with hierarchical names
+// (e.g. [database.pool] max-size) and arrays the elements of settings
+// holding several elements. Integers are given to settings in decimal,
+// floats and datetimes as written in the document.
+type Source interface {
+	congo.Source
+	Table(name string) Source
+	WriteDefaults(w io.Writer) error
+	SetLooseLoad(loose bool) Source
+}
+
+type tomlSource struct {
+	input     *input.Input
+	table     string
+	looseLoad bool
+	defaults  map[string]*congo.Setting
+}
+
+// Init initializes the toml source.
+func (s *tomlSource) Init(settings map[string]*congo.Setting) error {
+	s.defaults = settings
+	return nil
+}
+
+// Names returns the key that sets the setting with given name. Keys outside
+// of the root table are preceded by their table e.g. "[server] port".
+func (s *tomlSource) Names(setting string) []string {
+	path := s.locate(setting)
+	i := strings.LastIndex(path, congo.NameSeparator)
+	if i < 0 {
+		return []string{path}
+	}
+	return []string{"[" + path[:i] + "] " + path[i+len(congo.NameSeparator):]}
+}
+
+// locate returns the path of the setting with given name in the document.
+func (s *tomlSource) locate(name string) string {
+	if s.table == "" {
+		return name
+	}
+	return s.table + congo.NameSeparator + name
+}
+
+// Load loads the settings from the TOML document.
+// All settings that can't be set are reported as congo.Errors.
+func (s *tomlSource) Load(settings map[string]*congo.Setting) error {
+	data, err := s.input.Content(s.looseLoad)
+	if err != nil {
+		return fmt.Errorf("toml-source: couldn't load the toml-file because: %w", err)
+	}
+	root, err := parse(s.input.Name(), data)
+	if err != nil {
+		return fmt.Errorf("toml-source: couldn't load the toml-file because: %w", err)
+	}
+	node := root.Lookup(s.table)
+	if node == nil {
+		// Table doesn't exist
+		// We simply don't load any setting and use the defaults
+		return nil
+	}
+	return tree.Apply(node, sourceName, settings)
+}
+
+// WriteDefaults writes the default settings to given writer.
+// The help of every setting is written as comment above it.
+// If an error occurs nothing will be written.
+func (s *tomlSource) WriteDefaults(w io.Writer) error {
+	template, err := tree.NewTemplate(s.defaults, s.table)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	writeTable(&buffer, template, nil)
+	_, err = buffer.WriteTo(w)
+	return err
+}
+
+// writeTable writes the settings of given template as table with given
+// path followed by its sub-tables.
+func writeTable(buffer *bytes.Buffer, t *tree.Template, path []string) {
+	if len(t.Settings) > 0 && len(path) > 0 {
+		if buffer.Len() > 0 {
+			buffer.WriteString("\n")
+		}
+		keys := make([]string, len(path))
+		for i, key := range path {
+			keys[i] = formatKey(key)
+		}
+		buffer.WriteString("[" + strings.Join(keys, ".") + "]\n")
+	}
+	for _, key := range t.Keys {
+		setting, ok := t.Settings[key]
+		if !ok {
+			continue
+		}
+		if help := setting.Help(); help != "" {
+			for _, line := range strings.Split(help, "\n") {
+				buffer.WriteString("# " + line + "\n")
+			}
+		}
+		buffer.WriteString(formatKey(key) + " = " + literal(setting) + "\n")
+	}
+	for _, key := range t.Keys {
+		if group, ok := t.Groups[key]; ok {
+			writeTable(buffer, group, append(path[:len(path):len(path)], key))
+		}
+	}
+}
+
+// literal returns the default value of the setting as TOML value.
+// Booleans and numbers are written as such, everything else as string.
+func literal(setting *congo.Setting) string {
+	switch tree.KindOf(setting.Value) {
+	case tree.Bool:
+		if setting.DefValue == "true" || setting.DefValue == "false" {
+			return setting.DefValue
+		}
+	case tree.Number:
+		if decimalInt.MatchString(setting.DefValue) || float.MatchString(setting.DefValue) {
+			return setting.DefValue
+		}
+	}
+	return quote(setting.DefValue)
+}
+
+// formatKey returns key as bare key if possible and as quoted key otherwise.
+func formatKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quote(key)
+}
+
+// quote returns s as basic string.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// SetLooseLoad sets whether this source should complain if the file
+// doesn't exist. Default is true.
+func (s *tomlSource) SetLooseLoad(loose bool) Source {
+	s.looseLoad = loose
+	return s
+}
+
+// Table creates a sub-source that loads settings from a table of the
+// TOML document. The name of the table is given like in its header
+// e.g. "services.api".
+func (s *tomlSource) Table(name string) Source {
+	return &tomlSource{
+		s.input,
+		s.locate(name),
+		s.looseLoad,
+		s.defaults,
+	}
+}
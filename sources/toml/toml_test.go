@@ -0,0 +1,205 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+const content = `# Comment
+debug = true # trailing comment
+big = 9_223_372_036_854_775_807
+mask = 0xff
+ratio = 6.626e-34
+pi = 3.141_592_653_589_793
+started = 1979-05-27 07:32:00.999999-07:00
+name = "tab\tand \u00e9"
+path = 'C:\Users'
+tags = [
+  "a", # first
+  'b',
+]
+motd = """
+Roses are red
+Violets are \
+  blue"""
+labels = { team = "core", tier = 1 }
+server.port = 8080
+
+[database.pool]
+max-size = 10
+timeouts = ["1s", "2s"]
+`
+
+func TestTOMLSource_Load(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(content)))
+	debug := cfg.Bool("debug", false, "")
+	big := cfg.Int64("big", 0, "")
+	mask := cfg.Int("mask", 0, "")
+	ratio := cfg.String("ratio", "", "")
+	pi := cfg.Float64("pi", 0, "")
+	started := time.Time{}
+	cfg.TextVar(&started, "started", time.Time{}, "")
+	name := cfg.String("name", "", "")
+	path := cfg.String("path", "", "")
+	tags := cfg.StringSlice("tags", nil, "")
+	motd := cfg.String("motd", "", "")
+	labels := cfg.StringMap("labels", nil, "")
+	port := cfg.Int("server.port", 0, "")
+	maxSize := cfg.Int("database.pool.max-size", 0, "")
+	timeouts := cfg.DurationSlice("database.pool.timeouts", nil, "")
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if !*debug || *big != 9223372036854775807 || *mask != 255 || *pi != 3.141592653589793 {
+		t.Errorf("Expected scalars to be loaded.\nBut got: %v %v %v %v\n", *debug, *big, *mask, *pi)
+	}
+	if *ratio != "6.626e-34" {
+		t.Errorf("Expected float to be given as written.\nBut got: %q\n", *ratio)
+	}
+	expectedTime := time.Date(1979, 5, 27, 7, 32, 0, 999999000, time.FixedZone("", -7*60*60))
+	if !started.Equal(expectedTime) {
+		t.Errorf("Expected datetime %v.\nBut got: %v\n", expectedTime, started)
+	}
+	if *name != "tab\tand é" || *path != `C:\Users` || *motd != "Roses are red\nViolets are blue" {
+		t.Errorf("Expected strings to be loaded.\nBut got: %q %q %q\n", *name, *path, *motd)
+	}
+	if len(*tags) != 2 || (*tags)[1] != "b" || len(*labels) != 2 || (*labels)["tier"] != "1" {
+		t.Errorf("Expected array and inline table to be loaded.\nBut got: %v %v\n", *tags, *labels)
+	}
+	if *port != 8080 || *maxSize != 10 || len(*timeouts) != 2 || (*timeouts)[1] != 2*time.Second {
+		t.Errorf("Expected tables to be loaded.\nBut got: %v %v %v\n", *port, *maxSize, *timeouts)
+	}
+	expected := congo.Origin{Source: "toml", Raw: "10", Location: "<bytes>:22"}
+	if origin, _ := cfg.Origin("database.pool.max-size"); origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+}
+
+func TestTOMLSource_Table(t *testing.T) {
+	src := FromBytes([]byte(content))
+	cfg := congo.New("test", src.Table("database").Table("pool"))
+	maxSize := cfg.Int("max-size", 0, "")
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil || *maxSize != 10 {
+		t.Errorf("Expected setting to be loaded from table.\nBut got: %d (error: %v)\n", *maxSize, err)
+	}
+	if names := src.Table("database").(congo.Namer).Names("pool.max-size"); names[0] != "[database.pool] max-size" {
+		t.Errorf("Expected setting to be named after its table.\nBut got: %v\n", names)
+	}
+}
+
+func TestTOMLSource_Load_Errors(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(content)))
+	cfg.Int("name", 0, "")
+	cfg.Int("database", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var errs congo.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected both settings to be reported.\nBut got: %v\n", err)
+	}
+	var loadErr *congo.LoadError
+	if !errors.As(errs[1], &loadErr) || loadErr.Setting != "name" || loadErr.Location != "<bytes>:8" {
+		t.Errorf("Expected error to describe setting %q.\nBut got: %v\n", "name", errs[1])
+	}
+}
+
+func TestTOMLSource_Load_Invalid(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"a = 1\nb = 01\n", "<bytes>:2: invalid value"},
+		{"a = 1\na = 2\n", "<bytes>:2: duplicate key"},
+		{"[a]\n[a]\n", "<bytes>:2: table \"a\" is defined twice"},
+		{"a = 1\n[a]\n", "already defined as value"},
+		{"[[a]]\n", "arrays of tables"},
+		{"a = [[1]]\n", "nested arrays"},
+		{"a = \"open\n", "<bytes>:1: unterminated string"},
+		{"a = \"\\x\"\n", "invalid escape"},
+		{"a = 1 b = 2\n", "expected the end of the line"},
+		{"a = 0xffffffffffffffffff\n", "invalid integer"},
+		{"= 1\n", "expected a key"},
+	}
+	for _, test := range tests {
+		err := FromBytes([]byte(test.content)).Load(map[string]*congo.Setting{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q for %q.\nBut got: %v\n", test.expected, test.content, err)
+		}
+	}
+}
+
+func TestTOMLSource_Load_NotLoose(t *testing.T) {
+	settings := map[string]*congo.Setting{}
+	if err := FromFile("").Load(settings); err != nil {
+		t.Errorf("Expected load with non-existent file to work without errors.\nBut got error: %s\n", err)
+	}
+	if err := FromFile("").SetLooseLoad(false).Load(settings); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected load with non-existent file to fail with os.ErrNotExist.\nBut got: %v\n", err)
+	}
+}
+
+func TestTOMLSource_WriteDefaults(t *testing.T) {
+	src := FromBytes(nil)
+	cfg := congo.New("test", src)
+	port := cfg.Int("port", 80, "Port to listen on", congo.Min("1"))
+	ratio := cfg.Float64("ratio", 0.5, "")
+	name := cfg.String("name", "say \"hi\"", "")
+	timeout := cfg.Duration("database.timeout", time.Second, "")
+	host := cfg.String("database.primary.host", "localhost", "")
+	cfg.Init()
+
+	var buffer bytes.Buffer
+	if err := src.WriteDefaults(&buffer); err != nil {
+		t.Fatalf("Expected to write without problems.\nBut got error: %s\n", err)
+	}
+	expected := `name = "say \"hi\""
+# Port to listen on (min=1)
+port = 80
+ratio = 0.5
+
+[database]
+timeout = "1s"
+
+[database.primary]
+host = "localhost"
+`
+	if buffer.String() != expected {
+		t.Errorf("Expected defaults:\n%s\nBut got:\n%s\n", expected, buffer.String())
+	}
+
+	// The defaults are loaded unchanged.
+	*port, *ratio, *name, *timeout, *host = 0, 0, "", 0, ""
+	cfg = congo.New("test", FromBytes(buffer.Bytes()))
+	cfg.IntVar(port, "port", 0, "")
+	cfg.Float64Var(ratio, "ratio", 0, "")
+	cfg.StringVar(name, "name", "", "")
+	cfg.DurationVar(timeout, "database.timeout", 0, "")
+	cfg.StringVar(host, "database.primary.host", "", "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected defaults to be loadable.\nBut got error: %s\n", err)
+	}
+	if *port != 80 || *ratio != 0.5 || *name != "say \"hi\"" || *timeout != time.Second || *host != "localhost" {
+		t.Errorf("Expected defaults to be loaded unchanged.\nBut got: %v %v %q %v %q\n",
+			*port, *ratio, *name, *timeout, *host)
+	}
+}
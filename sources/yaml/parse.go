@@ -0,0 +1,428 @@
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/silentteacup/congo/internal/tree"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// parser builds the document of YAML data. It reads the data line by
+// line and uses the indentation of the lines to find nested nodes.
+type parser struct {
+	name  string
+	lines []string // raw lines of the data
+	pos   int      // index of the line read next
+}
+
+// parse parses the block mapping in data. Empty data is an empty mapping.
+func parse(name string, data []byte) (*tree.Node, error) {
+	p := &parser{name: name, lines: strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")}
+	indent, text, ok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if ok && text == "---" {
+		p.pos++
+		if indent, text, ok, err = p.peek(); err != nil {
+			return nil, err
+		}
+	}
+	root := tree.NewObject(name)
+	if ok && text != "..." {
+		if isItem(text) {
+			return nil, p.errorf(p.pos, "expected a mapping but got a sequence")
+		}
+		if root, err = p.mapping(indent); err != nil {
+			return nil, err
+		}
+	}
+	if _, text, ok, err = p.peek(); err != nil || !ok {
+		return root, err
+	}
+	switch text {
+	case "---":
+		return nil, p.errorf(p.pos, "several documents are not supported")
+	case "...":
+		p.pos++
+		if _, _, ok, err = p.peek(); err != nil || !ok {
+			return root, err
+		}
+	}
+	return nil, p.errorf(p.pos, "unexpected content")
+}
+
+// location returns the location of the line with given index.
+func (p *parser) location(i int) string {
+	return fmt.Sprintf("%s:%d", p.name, i+1)
+}
+
+// errorf returns an error describing a problem at the line with given index.
+func (p *parser) errorf(i int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", p.location(i), fmt.Sprintf(format, args...))
+}
+
+// peek returns the indentation and content (without comments) of the next
+// line that has content. Blank lines and comments are skipped. It doesn't
+// read the line; ok is false if there are no lines left.
+func (p *parser) peek() (indent int, text string, ok bool, err error) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos]
+		text = strings.TrimSpace(stripComment(raw))
+		if text == "" {
+			continue
+		}
+		indent = len(raw) - len(strings.TrimLeft(raw, " "))
+		if raw[indent] == '\t' {
+			return 0, "", false, p.errorf(p.pos, "tabs are not allowed for indentation")
+		}
+		return indent, text, true, nil
+	}
+	return 0, "", false, nil
+}
+
+// mapping reads the entries of a block mapping with given indentation.
+func (p *parser) mapping(indent int) (*tree.Node, error) {
+	node := tree.NewObject(p.location(p.pos))
+	for {
+		i, text, ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || i < indent || text == "---" || text == "..." {
+			return node, nil
+		}
+		if i > indent {
+			return nil, p.errorf(p.pos, "unexpected indentation")
+		}
+		if isItem(text) {
+			return nil, p.errorf(p.pos, "expected a key but got a sequence item")
+		}
+		number := p.pos
+		key, rest, err := p.splitKey(text)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		if _, ok := node.Children[key]; ok {
+			return nil, p.errorf(number, "duplicate key %q", key)
+		}
+		if node.Children[key], err = p.value(indent, rest, number); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// value reads the value of a key with given indentation. The value either
+// follows the key on the same line or is a block in the following lines.
+func (p *parser) value(indent int, rest string, number int) (*tree.Node, error) {
+	switch {
+	case rest == "":
+		i, text, ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		// Sequences may have the same indentation as the key they belong to.
+		if ok && i >= indent && isItem(text) {
+			return p.sequence(i)
+		}
+		if ok && i > indent {
+			return p.mapping(i)
+		}
+		return &tree.Node{Location: p.location(number)}, nil
+	case rest[0] == '|' || rest[0] == '>':
+		return p.blockScalar(indent, rest, number)
+	default:
+		return p.scalar(rest, number)
+	}
+}
+
+// sequence reads the items of a block sequence with given indentation.
+// Items must be scalars; null items are skipped.
+func (p *parser) sequence(indent int) (*tree.Node, error) {
+	node := &tree.Node{List: true, Location: p.location(p.pos)}
+	for {
+		i, text, ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || i < indent || !isItem(text) {
+			return node, nil
+		}
+		if i > indent {
+			return nil, p.errorf(p.pos, "unexpected indentation")
+		}
+		number := p.pos
+		p.pos++
+		item := strings.TrimSpace(text[1:])
+		var element *tree.Node
+		switch {
+		case item == "":
+			i, _, ok, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if ok && i > indent {
+				return nil, p.errorf(p.pos, "nested mappings and sequences in sequences are not supported")
+			}
+			continue
+		case isItem(item) || hasKey(item):
+			return nil, p.errorf(number, "nested mappings and sequences in sequences are not supported")
+		case item[0] == '|' || item[0] == '>':
+			element, err = p.blockScalar(indent, item, number)
+		default:
+			element, err = p.scalar(item, number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if element.Children != nil || element.List {
+			return nil, p.errorf(number, "nested mappings and sequences in sequences are not supported")
+		}
+		node.Values = append(node.Values, element.Values...)
+	}
+}
+
+// blockScalar reads a literal (|) or folded (>) block scalar whose lines are
+// indented further than given indentation. The header may strip (-) or keep (+)
+// trailing line breaks.
+func (p *parser) blockScalar(indent int, header string, number int) (*tree.Node, error) {
+	chomping := header[1:]
+	if chomping != "" && chomping != "-" && chomping != "+" {
+		return nil, p.errorf(number, "unsupported block scalar header %q", header)
+	}
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos]
+		if strings.TrimSpace(raw) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		i := len(raw) - len(strings.TrimLeft(raw, " "))
+		if i <= indent || blockIndent >= 0 && i < blockIndent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = i
+		}
+		lines = append(lines, raw[blockIndent:])
+	}
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var value string
+	if header[0] == '|' {
+		value = strings.Join(lines, "\n")
+	} else {
+		for i, line := range lines {
+			switch {
+			case line == "":
+				value += "\n"
+			case i > 0 && lines[i-1] != "":
+				value += " " + line
+			default:
+				value += line
+			}
+		}
+	}
+	switch {
+	case chomping == "+":
+		value += strings.Repeat("\n", trailing+1)
+	case chomping == "" && value != "":
+		value += "\n"
+	}
+	return scalarNode(value, p.location(number)), nil
+}
+
+// scalar reads a scalar that is given on a single line. Flow sequences
+// of scalars and empty flow mappings are supported as well.
+func (p *parser) scalar(text string, number int) (*tree.Node, error) {
+	location := p.location(number)
+	switch text[0] {
+	case '"', '\'':
+		value, rest, err := unquote(text)
+		if err != nil {
+			return nil, p.errorf(number, "%s", err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, p.errorf(number, "unexpected content after quoted scalar")
+		}
+		return scalarNode(value, location), nil
+	case '[':
+		return p.flowSequence(text, number)
+	case '{':
+		if strings.TrimSpace(text[1:]) != "}" {
+			return nil, p.errorf(number, "flow mappings are not supported")
+		}
+		return tree.NewObject(location), nil
+	case '&', '*', '!':
+		return nil, p.errorf(number, "anchors, aliases and tags are not supported")
+	}
+	if isNull(text) {
+		return &tree.Node{Location: location}, nil
+	}
+	return scalarNode(text, location), nil
+}
+
+// flowSequence reads a sequence of scalars like [a, "b", 'c'].
+func (p *parser) flowSequence(text string, number int) (*tree.Node, error) {
+	node := &tree.Node{List: true, Location: p.location(number)}
+	rest := strings.TrimSpace(text[1:])
+	for rest != "" && rest[0] != ']' {
+		var item string
+		quoted := rest[0] == '"' || rest[0] == '\''
+		if quoted {
+			var err error
+			if item, rest, err = unquote(rest); err != nil {
+				return nil, p.errorf(number, "%s", err)
+			}
+		} else {
+			end := strings.IndexAny(rest, ",]")
+			if end < 0 {
+				break
+			}
+			item, rest = strings.TrimSpace(rest[:end]), rest[end:]
+			if strings.ContainsAny(item, "[{") {
+				return nil, p.errorf(number, "nested mappings and sequences in sequences are not supported")
+			}
+			if isNull(item) {
+				item = ""
+			}
+		}
+		if item != "" || quoted {
+			node.Values = append(node.Values, tree.Scalar{Raw: item, Location: node.Location})
+		}
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		}
+	}
+	if rest != "]" {
+		return nil, p.errorf(number, "unterminated flow sequence")
+	}
+	return node, nil
+}
+
+// splitKey splits the line of a mapping entry into its key and the rest of
+// the line following the colon.
+func (p *parser) splitKey(text string) (key string, rest string, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		if key, rest, err = unquote(text); err != nil {
+			return "", "", p.errorf(p.pos, "%s", err)
+		}
+		rest = strings.TrimLeft(rest, " ")
+		if rest == ":" || strings.HasPrefix(rest, ": ") {
+			return key, strings.TrimSpace(rest[1:]), nil
+		}
+		return "", "", p.errorf(p.pos, "expected ':' after key %q", key)
+	}
+	i := keyEnd(text)
+	if i < 0 {
+		return "", "", p.errorf(p.pos, "expected a key followed by ':'")
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), nil
+}
+
+// keyEnd returns the index of the colon ending the key of a plain mapping
+// entry or -1 if text isn't a mapping entry.
+func keyEnd(text string) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasKey returns whether text starts a mapping entry.
+func hasKey(text string) bool {
+	if text[0] == '"' || text[0] == '\'' {
+		_, rest, err := unquote(text)
+		rest = strings.TrimLeft(rest, " ")
+		return err == nil && (rest == ":" || strings.HasPrefix(rest, ": "))
+	}
+	return keyEnd(text) >= 0
+}
+
+// isItem returns whether text is an item of a block sequence.
+func isItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isNull returns whether the plain scalar stands for null.
+func isNull(text string) bool {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return true
+	}
+	return false
+}
+
+// unquote reads the quoted scalar text starts with and returns its value
+// and the text following it. Single quoted scalars escape quotes by doubling
+// them, double quoted scalars use backslash escapes.
+func unquote(text string) (value string, rest string, err error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] != quote:
+		case quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case quote == '\'':
+			return strings.Replace(text[1:i], "''", "'", -1), text[i+1:], nil
+		default:
+			value, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid escape sequence in %s", text[:i+1])
+			}
+			return value, text[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted scalar")
+}
+
+// stripComment removes a comment from given line. Comments start with
+// '#' at the beginning of the line or after whitespace outside of quoted
+// scalars.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			// Quotes only start a quoted scalar at the beginning of a value.
+			before := strings.TrimRight(line[:i], " ")
+			if before == "" || strings.ContainsAny(before[len(before)-1:], ":-[,{") {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// scalarNode creates a node holding a single value.
+func scalarNode(raw string, location string) *tree.Node {
+	return &tree.Node{Values: []tree.Scalar{{Raw: raw, Location: location}}, Location: location}
+}
@@ -0,0 +1,174 @@
+// Package yaml provides a source loading settings from YAML documents.
+//
+// The source supports the subset of YAML commonly used for configuration:
+// block mappings, block sequences of scalars, plain, quoted and block scalars,
+// flow sequences of scalars and comments. Anchors, aliases, tags and several
+// documents in one file aren't supported.
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gitlab.com/silentteacup/congo"
+	"gitlab.com/silentteacup/congo/internal/input"
+	"gitlab.com/silentteacup/congo/internal/tree"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// New creates a new yaml source which reads from
+// given reader.
+func New(reader io.Reader) Source {
+	return createSource(reader)
+}
+
+// FromBytes creates a new yaml source directly from
+// the data that should be read.
+func FromBytes(content []byte) Source {
+	return createSource(content)
+}
+
+// FromFile creates a new yaml source which uses the
+// file at given path to load the configuration.
+func FromFile(path string) Source {
+	return createSource(path)
+}
+
+// createSource creates the yaml source with default values
+// using given source as source for the yaml-document.
+func createSource(source interface{}) Source {
+	return &yamlSource{input.New(source), "", true, nil}
+}
+
+// sourceName is the name used for the origin of settings set by this source.
+const sourceName = "yaml"
+
+// Source a yaml source uses a YAML document to load settings.
+//
+// Nested mappings contain the settings with hierarchical names
+// (e.g. "database.pool.max-size") and sequences the elements of settings
+// holding several elements.
+type Source interface {
+	congo.Source
+	Path(path string) Source
+	WriteDefaults(w io.Writer) error
+	SetLooseLoad(loose bool) Source
+}
+
+type yamlSource struct {
+	input     *input.Input
+	path      string
+	looseLoad bool
+	defaults  map[string]*congo.Setting
+}
+
+// Init initializes the yaml source.
+func (s *yamlSource) Init(settings map[string]*congo.Setting) error {
+	s.defaults = settings
+	return nil
+}
+
+// Names returns the path of the value that sets the setting with given name.
+func (s *yamlSource) Names(setting string) []string {
+	return []string{s.locate(setting)}
+}
+
+// locate returns the path of the setting with given name in the document.
+func (s *yamlSource) locate(name string) string {
+	if s.path == "" {
+		return name
+	}
+	return s.path + congo.NameSeparator + name
+}
+
+// Load loads the settings from the YAML document.
+// All settings that can't be set are reported as congo.Errors.
+func (s *yamlSource) Load(settings map[string]*congo.Setting) error {
+	data, err := s.input.Content(s.looseLoad)
+	if err != nil {
+		return fmt.Errorf("yaml-source: couldn't load the yaml-file because: %w", err)
+	}
+	root, err := parse(s.input.Name(), data)
+	if err != nil {
+		return fmt.Errorf("yaml-source: couldn't load the yaml-file because: %w", err)
+	}
+	node := root.Lookup(s.path)
+	if node == nil {
+		// The mapping doesn't exist.
+		// We simply don't load any setting and use the defaults.
+		return nil
+	}
+	return tree.Apply(node, sourceName, settings)
+}
+
+// WriteDefaults writes the default settings to given writer.
+// The help of every setting is written as comment above it.
+// If an error occurs nothing will be written.
+func (s *yamlSource) WriteDefaults(w io.Writer) error {
+	template, err := tree.NewTemplate(s.defaults, s.path)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	writeTemplate(&buffer, template, "")
+	_, err = buffer.WriteTo(w)
+	return err
+}
+
+// writeTemplate writes the settings of given template as block mapping.
+func writeTemplate(buffer *bytes.Buffer, t *tree.Template, indent string) {
+	for _, key := range t.Keys {
+		if group, ok := t.Groups[key]; ok {
+			buffer.WriteString(indent + format(key) + ":\n")
+			writeTemplate(buffer, group, indent+"  ")
+			continue
+		}
+		setting := t.Settings[key]
+		if help := setting.Help(); help != "" {
+			for _, line := range strings.Split(help, "\n") {
+				buffer.WriteString(indent + "# " + line + "\n")
+			}
+		}
+		buffer.WriteString(indent + format(key) + ": " + format(setting.DefValue) + "\n")
+	}
+}
+
+// format returns s as plain scalar if it is read back unchanged
+// and as double quoted scalar otherwise.
+func format(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || isNull(s) || strings.ContainsAny(s[:1], "?:,[]{}#&*!|>'\"%@`") ||
+		s[0] == '-' && (len(s) == 1 || s[1] == ' ') || strings.HasSuffix(s, ":") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strconv.Quote(s) != `"`+s+`"` {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// SetLooseLoad sets whether this source should complain if the file
+// doesn't exist. Default is true.
+func (s *yamlSource) SetLooseLoad(loose bool) Source {
+	s.looseLoad = loose
+	return s
+}
+
+// Path creates a sub-source that loads settings from a nested mapping
+// of the YAML document. The path names the mapping with its keys
+// separated by congo.NameSeparator e.g. "services.api".
+func (s *yamlSource) Path(path string) Source {
+	return &yamlSource{
+		s.input,
+		s.locate(path),
+		s.looseLoad,
+		s.defaults,
+	}
+}
@@ -0,0 +1,195 @@
+package yaml
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/silentteacup/congo"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+const content = `---
+# Comment
+debug: true # trailing comment
+name: "quoted # not a comment"
+single: 'it''s'
+url: http://example.com/#anchor
+nothing: ~
+tags:
+- a
+- b
+ports: [80, "443"]
+labels:
+  team: core
+  tier: 1
+certificate: |
+  line 1
+  line 2
+
+folded: >-
+  a
+  b
+database:
+  pool:
+    max-size: 10
+    timeouts:
+      - 1s
+      - 2s
+`
+
+func TestYAMLSource_Load(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(content)))
+	debug := cfg.Bool("debug", false, "")
+	name := cfg.String("name", "", "")
+	single := cfg.String("single", "", "")
+	url := cfg.String("url", "", "")
+	nothing := cfg.String("nothing", "default", "")
+	tags := cfg.StringSlice("tags", nil, "")
+	ports := cfg.IntSlice("ports", nil, "")
+	labels := cfg.StringMap("labels", nil, "")
+	certificate := cfg.String("certificate", "", "")
+	folded := cfg.String("folded", "", "")
+	maxSize := cfg.Int("database.pool.max-size", 0, "")
+	timeouts := cfg.DurationSlice("database.pool.timeouts", nil, "")
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected to load without problems.\nBut got error: %s\n", err)
+	}
+	if !*debug || *name != "quoted # not a comment" || *single != "it's" || *url != "http://example.com/#anchor" {
+		t.Errorf("Expected scalars to be loaded.\nBut got: %v %q %q %q\n", *debug, *name, *single, *url)
+	}
+	if *nothing != "default" {
+		t.Errorf("Expected null to be ignored.\nBut got: %q\n", *nothing)
+	}
+	if len(*tags) != 2 || (*tags)[1] != "b" || len(*ports) != 2 || (*ports)[1] != 443 {
+		t.Errorf("Expected sequences to be loaded into slices.\nBut got: %v %v\n", *tags, *ports)
+	}
+	if len(*labels) != 2 || (*labels)["tier"] != "1" {
+		t.Errorf("Expected mapping to be loaded into map.\nBut got: %v\n", *labels)
+	}
+	if *certificate != "line 1\nline 2\n" || *folded != "a b" {
+		t.Errorf("Expected block scalars to be loaded.\nBut got: %q %q\n", *certificate, *folded)
+	}
+	if *maxSize != 10 || len(*timeouts) != 2 || (*timeouts)[1] != 2*time.Second {
+		t.Errorf("Expected nested settings to be loaded.\nBut got: %v %v\n", *maxSize, *timeouts)
+	}
+	expected := congo.Origin{Source: "yaml", Raw: "2s", Location: "<bytes>:27"}
+	if origin, _ := cfg.Origin("database.pool.timeouts"); origin != expected {
+		t.Errorf("Expected origin to be %+v.\nBut got: %+v\n", expected, origin)
+	}
+}
+
+func TestYAMLSource_Path(t *testing.T) {
+	src := FromBytes([]byte(content))
+	cfg := congo.New("test", src.Path("database.pool"))
+	maxSize := cfg.Int("max-size", 0, "")
+	cfg.Init()
+
+	if err := cfg.Load(); err != nil || *maxSize != 10 {
+		t.Errorf("Expected setting to be loaded from path.\nBut got: %d (error: %v)\n", *maxSize, err)
+	}
+}
+
+func TestYAMLSource_Load_Errors(t *testing.T) {
+	cfg := congo.New("test", FromBytes([]byte(content)))
+	cfg.Int("name", 0, "")
+	cfg.Int("database", 0, "")
+	cfg.Init()
+
+	err := cfg.Load()
+	var errs congo.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected both settings to be reported.\nBut got: %v\n", err)
+	}
+	var loadErr *congo.LoadError
+	if !errors.As(errs[1], &loadErr) || loadErr.Setting != "name" || loadErr.Location != "<bytes>:4" {
+		t.Errorf("Expected error to describe setting %q.\nBut got: %v\n", "name", errs[1])
+	}
+}
+
+func TestYAMLSource_Load_Invalid(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"a: 1\n  b: 2\n", "<bytes>:2: unexpected indentation"},
+		{"a: 1\na: 2\n", "<bytes>:2: duplicate key"},
+		{"- a\n", "<bytes>:1: expected a mapping"},
+		{"a:\n\t- b\n", "<bytes>:2: tabs are not allowed"},
+		{"a:\n  - b: c\n", "<bytes>:2: nested mappings"},
+		{"a: &anchor b\n", "<bytes>:1: anchors"},
+		{"a: 'open\n", "<bytes>:1: unterminated"},
+		{"a: [1, 2\n", "<bytes>:1: unterminated flow sequence"},
+		{"a\n", "<bytes>:1: expected a key"},
+		{"a: 1\n---\nb: 2\n", "<bytes>:2: several documents"},
+	}
+	for _, test := range tests {
+		err := FromBytes([]byte(test.content)).Load(map[string]*congo.Setting{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q for %q.\nBut got: %v\n", test.expected, test.content, err)
+		}
+	}
+}
+
+func TestYAMLSource_Load_NotLoose(t *testing.T) {
+	settings := map[string]*congo.Setting{}
+	if err := FromFile("").Load(settings); err != nil {
+		t.Errorf("Expected load with non-existent file to work without errors.\nBut got error: %s\n", err)
+	}
+	if err := FromFile("").SetLooseLoad(false).Load(settings); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected load with non-existent file to fail with os.ErrNotExist.\nBut got: %v\n", err)
+	}
+}
+
+func TestYAMLSource_WriteDefaults(t *testing.T) {
+	src := FromBytes(nil).Path("app")
+	cfg := congo.New("test", src)
+	port := cfg.Int("port", 80, "Port to listen on", congo.Min("1"))
+	name := cfg.String("name", "null", "")
+	greeting := cfg.String("greeting", "hello: world", "")
+	timeout := cfg.Duration("database.timeout", time.Second, "")
+	cfg.Init()
+
+	var buffer bytes.Buffer
+	if err := src.WriteDefaults(&buffer); err != nil {
+		t.Fatalf("Expected to write without problems.\nBut got error: %s\n", err)
+	}
+	expected := `app:
+  database:
+    timeout: 1s
+  greeting: "hello: world"
+  name: "null"
+  # Port to listen on (min=1)
+  port: 80
+`
+	if buffer.String() != expected {
+		t.Errorf("Expected defaults:\n%s\nBut got:\n%s\n", expected, buffer.String())
+	}
+
+	// The defaults are loaded unchanged.
+	*port, *name, *greeting, *timeout = 0, "", "", 0
+	cfg = congo.New("test", FromBytes(buffer.Bytes()).Path("app"))
+	cfg.IntVar(port, "port", 0, "")
+	cfg.StringVar(name, "name", "", "")
+	cfg.StringVar(greeting, "greeting", "", "")
+	cfg.DurationVar(timeout, "database.timeout", 0, "")
+	cfg.Init()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Expected defaults to be loadable.\nBut got error: %s\n", err)
+	}
+	if *port != 80 || *name != "null" || *greeting != "hello: world" || *timeout != time.Second {
+		t.Errorf("Expected defaults to be loaded unchanged.\nBut got: %v %q %q %v\n", *port, *name, *greeting, *timeout)
+	}
+}
@@ -0,0 +1,32 @@
+package congo
+
+import (
+	"testing"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"max-users", "min-users", "port", "database.host"}
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"max-user", "max-users"},
+		{"mxa-users", "max-users"},
+		{"prot", ""},
+		{"database.hots", "database.host"},
+		{"timeout", ""},
+	}
+	for _, test := range tests {
+		if suggestion := Suggest(test.key, candidates); suggestion != test.expected {
+			t.Errorf("Expected %q to be suggested for %q.\nBut got: %q\n", test.expected, test.key, suggestion)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package congo
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// Namer is implemented by sources that can tell how a setting is named
+// within them. The names are listed in the usage of the configuration.
+type Namer interface {
+	// Names returns the ways the setting with given name can be set using
+	// this source e.g. "-max-users" or "APP_MAX_USERS".
+	Names(setting string) []string
+}
+
+// Attacher is implemented by sources that need to know the configuration
+// they belong to e.g. to print its usage.
+type Attacher interface {
+	// Attach is called by Init() before any source is initialized.
+	Attach(Congo)
+}
+
+// Name returns the name of the configuration.
+func (c *congo) Name() string {
+	return c.name
+}
+
+// PrintUsage writes the usage of all settings to given writer.
+// Every setting is listed with its type, default value and help followed
+// by the names sources implementing Namer know it by.
+func (c *congo) PrintUsage(w io.Writer) {
+	if c.name == "" {
+		fmt.Fprintln(w, "Usage:")
+	} else {
+		fmt.Fprintf(w, "Usage of %s:\n", c.name)
+	}
+	for _, name := range Names(c.settings) {
+		setting := c.settings[name]
+		line := "  " + name + " " + typeName(setting.Value)
+		if setting.DefValue != "" {
+			if _, ok := setting.Value.(*stringValue); ok {
+				line += fmt.Sprintf(" (default %q)", setting.DefValue)
+			} else {
+				line += fmt.Sprintf(" (default %s)", setting.DefValue)
+			}
+		}
+		fmt.Fprintln(w, line)
+		if help := setting.Help(); help != "" {
+			// Indent multi-line help like the flag package does.
+			fmt.Fprintf(w, "    \t%s\n", strings.Replace(help, "\n", "\n    \t", -1))
+		}
+		if names := c.sourceNames(name); len(names) > 0 {
+			fmt.Fprintf(w, "    \tset by: %s\n", strings.Join(names, ", "))
+		}
+	}
+}
+
+// sourceNames returns the names the sources know the setting with given
+// name by, ordered by the priority of the sources.
+func (c *congo) sourceNames(setting string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, source := range c.sources {
+		namer, ok := source.(Namer)
+		if !ok {
+			continue
+		}
+		for _, name := range namer.Names(setting) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// typeName returns the name of the type held by given value.
+// Values that don't expose their value using Get are simply called "value".
+func typeName(v Value) string {
+	getter, ok := v.(interface {
+		Get() interface{}
+	})
+	if !ok {
+		return "value"
+	}
+	t := reflect.TypeOf(getter.Get())
+	if t == nil {
+		return "value"
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
@@ -0,0 +1,71 @@
+package congo
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// namerSource is a source that knows settings by an upper case name.
+type namerSource struct {
+	testSource
+	attached Congo
+}
+
+func (n *namerSource) Names(setting string) []string {
+	return []string{strings.ToUpper(setting)}
+}
+
+func (n *namerSource) Attach(c Congo) {
+	n.attached = c
+}
+
+func TestCongo_PrintUsage(t *testing.T) {
+	s := &namerSource{}
+	c := New("test", s, &testSource{})
+	c.Uint("max-users", 60, "Maximum number of users", Min("1"))
+	c.String("host", "localhost", "")
+	c.TextVar(&net.IP{}, "ip", nil, "Address to bind")
+	c.Var(&mockValue{}, "custom", "", Required())
+	var buffer bytes.Buffer
+	c.PrintUsage(&buffer)
+
+	expected := "Usage of test:\n" +
+		"  custom value\n" +
+		"    \t(required)\n" +
+		"    \tset by: CUSTOM\n" +
+		"  host string (default \"localhost\")\n" +
+		"    \tset by: HOST\n" +
+		"  ip net.IP\n" +
+		"    \tAddress to bind\n" +
+		"    \tset by: IP\n" +
+		"  max-users uint (default 60)\n" +
+		"    \tMaximum number of users (min=1)\n" +
+		"    \tset by: MAX-USERS\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected usage:\n%s\nBut got:\n%s\n", expected, buffer.String())
+	}
+}
+
+func TestCongo_Init_Attach(t *testing.T) {
+	s := &namerSource{}
+	c := New("test", s)
+	if err := c.Init(); err != nil {
+		t.Errorf("Expected to init without problems.\nBut got error: %s\n", err)
+	}
+	if s.attached != c {
+		t.Errorf("Expected source to be attached to the configuration.\nBut got: %v\n", s.attached)
+	}
+	if c.Name() != "test" {
+		t.Errorf("Expected name %q.\nBut got: %q\n", "test", c.Name())
+	}
+}
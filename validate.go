@@ -0,0 +1,438 @@
+package congo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// ErrInvalid is the cause of errors for values that violate a rule.
+var ErrInvalid = errors.New("invalid value")
+
+// Rule is a rule the value of a setting must follow. Rules are checked
+// after all sources are loaded. Rules of settings holding several elements
+// (slices and maps) apply to each element unless they are about the length.
+type Rule interface {
+	// Validate returns an error if the value violates the rule.
+	Validate(Value) error
+	// String describes the rule e.g. "min=1".
+	String() string
+}
+
+// Rules adds custom rules to a setting.
+func Rules(rules ...Rule) Option {
+	return func(s *Setting) {
+		s.Rules = append(s.Rules, rules...)
+	}
+}
+
+// Min requires numbers and durations to be at least the given bound.
+// The bound is given in the same form the value of the setting is set
+// e.g. "1" or "5m".
+//
+// When using a struct the tag `min:"<bound>"` has the same effect.
+func Min(bound string) Option {
+	return Rules(&boundRule{"min", bound, func(cmp int) bool { return cmp >= 0 }})
+}
+
+// Max requires numbers and durations to be at most the given bound.
+// The bound is given in the same form the value of the setting is set
+// e.g. "10" or "1h".
+//
+// When using a struct the tag `max:"<bound>"` has the same effect.
+func Max(bound string) Option {
+	return Rules(&boundRule{"max", bound, func(cmp int) bool { return cmp <= 0 }})
+}
+
+// OneOf requires the value to be one of the given values.
+//
+// When using a struct the tag `oneof:"<value> <value>..."` (separated by spaces)
+// has the same effect.
+func OneOf(values ...string) Option {
+	return Rules(oneOfRule(values))
+}
+
+// Pattern requires the value to match the given regular expression.
+// It panics if the expression can't be compiled.
+//
+// When using a struct the tag `pattern:"<expression>"` has the same effect.
+func Pattern(expr string) Option {
+	return Rules(&patternRule{regexp.MustCompile(expr)})
+}
+
+// NonZero requires the value to differ from the zero value of its type
+// e.g. 0, "" or an empty list.
+//
+// When using a struct the tag `nonzero:"true"` has the same effect.
+func NonZero() Option {
+	return Rules(nonZeroRule{})
+}
+
+// MinLen requires strings to have at least n characters and slices or maps
+// to have at least n elements.
+//
+// When using a struct the tag `minlen:"<n>"` has the same effect.
+func MinLen(n int) Option {
+	return Rules(&lenRule{"minlen", n, func(l, n int) bool { return l >= n }})
+}
+
+// MaxLen requires strings to have at most n characters and slices or maps
+// to have at most n elements.
+//
+// When using a struct the tag `maxlen:"<n>"` has the same effect.
+func MaxLen(n int) Option {
+	return Rules(&lenRule{"maxlen", n, func(l, n int) bool { return l <= n }})
+}
+
+// get returns the value held by v. Pointers are dereferenced.
+// Returns nil if v doesn't provide a Get() method.
+func get(v Value) interface{} {
+	getter, ok := v.(interface {
+		Get() interface{}
+	})
+	if !ok {
+		return nil
+	}
+	value := reflect.ValueOf(getter.Get())
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if !value.IsValid() || value.Kind() == reflect.Ptr {
+		return nil
+	}
+	return value.Interface()
+}
+
+// elements returns the string representations of the elements of v.
+// Values not holding several elements are their only element.
+func elements(v Value) []string {
+	switch a := get(v).(type) {
+	case []string:
+		return a
+	case []int:
+		e := make([]string, len(a))
+		for i, n := range a {
+			e[i] = strconv.Itoa(n)
+		}
+		return e
+	case []time.Duration:
+		e := make([]string, len(a))
+		for i, d := range a {
+			e[i] = d.String()
+		}
+		return e
+	case map[string]string:
+		e := make([]string, 0, len(a))
+		for _, value := range a {
+			e = append(e, value)
+		}
+		return e
+	default:
+		return []string{v.String()}
+	}
+}
+
+// boundRule checks numbers and durations against a bound.
+type boundRule struct {
+	name  string
+	bound string
+	ok    func(cmp int) bool // whether the result of comparing a value with the bound is ok
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// isNumber returns whether values of given type are compared with bounds
+// directly. Values of all other types are parsed from their string representation.
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseBound parses given bound for numbers or durations of given type.
+// It returns a function comparing a value of the type with the bound like
+// strings.Compare. Integers are compared as integers, so they don't lose
+// precision.
+func parseBound(t reflect.Type, bound string) (func(reflect.Value) int, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var b int64
+		var err error
+		if t == durationType {
+			var d time.Duration
+			d, err = time.ParseDuration(bound)
+			b = int64(d)
+		} else {
+			b, err = strconv.ParseInt(bound, 10, 64)
+		}
+		return func(v reflect.Value) int { return compare(v.Int() < b, v.Int() > b) }, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b, err := strconv.ParseUint(bound, 10, 64)
+		return func(v reflect.Value) int { return compare(v.Uint() < b, v.Uint() > b) }, err
+	default:
+		b, err := strconv.ParseFloat(bound, 64)
+		return func(v reflect.Value) int { return compare(v.Float() < b, v.Float() > b) }, err
+	}
+}
+
+// compare returns -1 if less, 1 if greater and 0 otherwise.
+func compare(less bool, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// numbers returns the numbers or durations held by v. Values of unknown
+// types are parsed from their string representation as float64.
+func numbers(v Value) ([]reflect.Value, error) {
+	value := reflect.ValueOf(get(v))
+	if value.Kind() == reflect.Slice && isNumber(value.Type().Elem()) {
+		values := make([]reflect.Value, value.Len())
+		for i := range values {
+			values[i] = value.Index(i)
+		}
+		return values, nil
+	}
+	if value.IsValid() && isNumber(value.Type()) {
+		return []reflect.Value{value}, nil
+	}
+	n, err := strconv.ParseFloat(v.String(), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q isn't a number", ErrInvalid, v.String())
+	}
+	return []reflect.Value{reflect.ValueOf(n)}, nil
+}
+
+func (r *boundRule) Validate(v Value) error {
+	values, err := numbers(v)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cmp, err := parseBound(values[0].Type(), r.bound)
+	if err != nil {
+		return fmt.Errorf("invalid bound of rule %s: %s", r, err)
+	}
+	for _, value := range values {
+		if !r.ok(cmp(value)) {
+			return fmt.Errorf("%w: violates %s", ErrInvalid, r)
+		}
+	}
+	return nil
+}
+
+func (r *boundRule) String() string { return r.name + "=" + r.bound }
+
+// oneOfRule checks whether values are part of a set of values.
+type oneOfRule []string
+
+func (r oneOfRule) Validate(v Value) error {
+	for _, e := range elements(v) {
+		found := false
+		for _, allowed := range r {
+			found = found || e == allowed
+		}
+		if !found {
+			return fmt.Errorf("%w: %q violates %s", ErrInvalid, e, r)
+		}
+	}
+	return nil
+}
+
+func (r oneOfRule) String() string { return "oneof=" + strings.Join(r, " ") }
+
+// patternRule checks values against a regular expression.
+type patternRule struct {
+	expr *regexp.Regexp
+}
+
+func (r *patternRule) Validate(v Value) error {
+	for _, e := range elements(v) {
+		if !r.expr.MatchString(e) {
+			return fmt.Errorf("%w: %q violates %s", ErrInvalid, e, r)
+		}
+	}
+	return nil
+}
+
+func (r *patternRule) String() string { return "pattern=" + r.expr.String() }
+
+// length returns the number of characters of strings and the number of
+// elements of slices and maps.
+func length(v Value) int {
+	value := reflect.ValueOf(get(v))
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len()
+	default:
+		return utf8.RuneCountInString(v.String())
+	}
+}
+
+// nonZeroRule checks that values aren't the zero value of their type.
+type nonZeroRule struct{}
+
+func (r nonZeroRule) Validate(v Value) error {
+	value := reflect.ValueOf(get(v))
+	var zero bool
+	switch value.Kind() {
+	case reflect.Invalid:
+		zero = v.String() == ""
+	case reflect.Slice, reflect.Map:
+		zero = value.Len() == 0
+	default:
+		zero = reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+	}
+	if zero {
+		return fmt.Errorf("%w: violates %s", ErrInvalid, r)
+	}
+	return nil
+}
+
+func (r nonZeroRule) String() string { return "nonzero" }
+
+// lenRule checks the length of values.
+type lenRule struct {
+	name string
+	n    int
+	ok   func(length int, n int) bool
+}
+
+func (r *lenRule) Validate(v Value) error {
+	if !r.ok(length(v), r.n) {
+		return fmt.Errorf("%w: violates %s", ErrInvalid, r)
+	}
+	return nil
+}
+
+func (r *lenRule) String() string { return r.name + "=" + strconv.Itoa(r.n) }
+
+const (
+	minTag     = "min"
+	maxTag     = "max"
+	oneOfTag   = "oneof"
+	patternTag = "pattern"
+	nonZeroTag = "nonzero"
+	minLenTag  = "minlen"
+	maxLenTag  = "maxlen"
+)
+
+// ruleOptions returns the options for the rules described by the tags of given field.
+// It panics if a tag can't be parsed, so typos fail when the struct is registered.
+func ruleOptions(f reflect.StructField) []Option {
+	var opts []Option
+	if bound, ok := f.Tag.Lookup(minTag); ok {
+		opts = append(opts, Min(checkBound(f, minTag, bound)))
+	}
+	if bound, ok := f.Tag.Lookup(maxTag); ok {
+		opts = append(opts, Max(checkBound(f, maxTag, bound)))
+	}
+	if values, ok := f.Tag.Lookup(oneOfTag); ok {
+		opts = append(opts, OneOf(strings.Fields(values)...))
+	}
+	if expr, ok := f.Tag.Lookup(patternTag); ok {
+		if _, err := regexp.Compile(expr); err != nil {
+			panic(fmt.Sprintf("invalid %s tag of field %s: %s", patternTag, f.Name, err))
+		}
+		opts = append(opts, Pattern(expr))
+	}
+	if boolTag(f, nonZeroTag) {
+		opts = append(opts, NonZero())
+	}
+	if n, ok := f.Tag.Lookup(minLenTag); ok {
+		opts = append(opts, MinLen(atoi(f, minLenTag, n)))
+	}
+	if n, ok := f.Tag.Lookup(maxLenTag); ok {
+		opts = append(opts, MaxLen(atoi(f, maxLenTag, n)))
+	}
+	return opts
+}
+
+// checkBound returns the bound of a tag of given field.
+// It panics if the bound isn't a number or duration matching the type of the field.
+func checkBound(f reflect.StructField, tag string, bound string) string {
+	t := f.Type
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if !isNumber(t) {
+		// Values of other types are parsed as float64 (see numbers).
+		t = reflect.TypeOf(float64(0))
+	}
+	if _, err := parseBound(t, bound); err != nil {
+		panic(fmt.Sprintf("invalid %s tag of field %s: %s", tag, f.Name, err))
+	}
+	return bound
+}
+
+// atoi converts the value of a tag of given field to an int.
+// It panics if the value isn't an int.
+func atoi(f reflect.StructField, tag string, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s tag of field %s: %s", tag, f.Name, err))
+	}
+	return n
+}
+
+// check reports required settings that weren't set with ErrRequired and
+// validates all others. Groups are checked entry by entry first and are
+// set if they have at least one entry.
+func (s *Setting) check() error {
+	group, ok := s.Value.(*groupValue)
+	if !ok {
+		if s.Required && s.Origin == nil {
+			return &SettingError{s.Name, ErrRequired}
+		}
+		return s.validate()
+	}
+	var errs Errors
+	errs.Append(group.check())
+	if s.Required && len(group.keys) == 0 {
+		errs.Append(&SettingError{s.Name, ErrRequired})
+	} else {
+		errs.Append(s.validate())
+	}
+	return errs.Err()
+}
+
+// validate checks the value of the setting against all its rules.
+// The errors of violated rules are reported as *LoadError.
+func (s *Setting) validate() error {
+	var errs Errors
+	for _, rule := range s.Rules {
+		if err := rule.Validate(s.Value); err != nil {
+			origin := Origin{Source: DefaultSource}
+			if s.Origin != nil {
+				origin = *s.Origin
+			}
+			errs.Append(&LoadError{s.Name, origin.Source, origin.Location, s.Value.String(), err})
+		}
+	}
+	return errs.Err()
+}
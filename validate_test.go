@@ -0,0 +1,147 @@
+package congo
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+/*
+Copyright (c) 2018 Peter Werner. All rights reserved.
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at http://mozilla.org/MPL/2.0/.
+*/
+
+// validation describes a value that is checked against a rule.
+type validation struct {
+	opt   Option
+	value Value
+	valid bool
+}
+
+func TestRules(t *testing.T) {
+	i, d, s, l := 5, time.Minute, "abc", []int{1, 7}
+	m := map[string]string{"a": "x"}
+	var empty []string
+	var ip net.IP
+	// Integers above 2^53 can't be told apart as float64.
+	u, n := uint64(math.MaxUint64), int64(1<<53+1)
+	validations := []validation{
+		{Min("5"), newIntValue(i, &i), true},
+		{Min("6"), newIntValue(i, &i), false},
+		{Max("5"), newIntValue(i, &i), true},
+		{Max("4"), newIntValue(i, &i), false},
+		{Min("30s"), newDurationValue(d, &d), true},
+		{Max("30s"), newDurationValue(d, &d), false},
+		{Max("7"), newIntSliceValue(l, &l, ","), true},
+		{Max("6"), newIntSliceValue(l, &l, ","), false},
+		{Max("18446744073709551614"), newUint64Value(u, &u), false},
+		{Min("18446744073709551615"), newUint64Value(u, &u), true},
+		{Max("9007199254740992"), newInt64Value(n, &n), false},
+		{Min("9007199254740993"), newInt64Value(n, &n), true},
+		{OneOf("abc", "def"), newStringValue(s, &s), true},
+		{OneOf("def"), newStringValue(s, &s), false},
+		{OneOf("x", "y"), newStringMapValue(m, &m, ","), true},
+		{Pattern("^[a-c]+$"), newStringValue(s, &s), true},
+		{Pattern("^[a-b]+$"), newStringValue(s, &s), false},
+		{NonZero(), newIntValue(i, &i), true},
+		{NonZero(), newStringSliceValue(empty, &empty, ","), false},
+		{NonZero(), newTextValue(nil, &ip), false},
+		{MinLen(3), newStringValue(s, &s), true},
+		{MinLen(4), newStringValue(s, &s), false},
+		{MaxLen(2), newIntSliceValue(l, &l, ","), true},
+		{MaxLen(1), newIntSliceValue(l, &l, ","), false},
+	}
+	for _, v := range validations {
+		setting := &Setting{Name: "test", Value: v.value}
+		v.opt(setting)
+		err := setting.validate()
+		if v.valid && err != nil {
+			t.Errorf("Expected %q to follow rule %s.\nBut got error: %s\n",
+				v.value, setting.Rules[0], err)
+		}
+		if !v.valid && !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected %q to violate rule %s.\nBut got: %v\n",
+				v.value, setting.Rules[0], err)
+		}
+	}
+}
+
+func TestCongo_Load_Rules(t *testing.T) {
+	settings := struct {
+		Port  int    `name:"port" min:"1" max:"65535" usage:"Port to listen on"`
+		Level string `name:"level" oneof:"debug info" required:"true"`
+		Name  string `name:"name" nonzero:"true" maxlen:"3"`
+	}{Port: 80, Level: "info"}
+	c, s := setupTestCongo()
+	c.Using(&settings)
+	c.Init()
+
+	help := s.InitParam["port"].Help()
+	if help != "Port to listen on (min=1, max=65535)" {
+		t.Errorf("Expected rules to be part of the help.\nBut got: %q\n", help)
+	}
+	help = s.InitParam["level"].Help()
+	if help != "(required, oneof=debug info)" {
+		t.Errorf("Expected rules to be part of the help.\nBut got: %q\n", help)
+	}
+
+	s.LoadFunc = func(settings map[string]*Setting) error {
+		return settings["port"].Set("0", Origin{Source: "test", Location: "here"})
+	}
+	err := c.Load()
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expected all violations to be reported.\nBut got: %v\n", err)
+	}
+	var loadErr *LoadError
+	if !errors.Is(errs[0], ErrRequired) {
+		t.Errorf("Expected missing %q to be reported.\nBut got: %v\n", "level", errs[0])
+	}
+	if !errors.As(errs[1], &loadErr) || loadErr.Setting != "name" || loadErr.Source != DefaultSource {
+		t.Errorf("Expected violation of default of %q to be reported.\nBut got: %v\n", "name", errs[1])
+	}
+	if !errors.As(errs[2], &loadErr) || loadErr.Setting != "port" || loadErr.Raw != "0" ||
+		loadErr.Source != "test" || !errors.Is(loadErr, ErrInvalid) {
+		t.Errorf("Expected violation of %q to be reported.\nBut got: %v\n", "port", errs[2])
+	}
+}
+
+func TestUsing_InvalidRuleTags(t *testing.T) {
+	invalid := []interface{}{
+		&struct {
+			Port int `min:"one"`
+		}{},
+		&struct {
+			Port int `max:"1.5"`
+		}{},
+		&struct {
+			Timeout time.Duration `max:"10"`
+		}{},
+		&struct {
+			Ports []uint `min:"-1"`
+		}{},
+		&struct {
+			Name string `pattern:"[a-"`
+		}{},
+		&struct {
+			Name string `nonzero:"yes"`
+		}{},
+		&struct {
+			Name string `required:"ture"`
+		}{},
+		&struct {
+			Password string `secret:"yes"`
+		}{},
+	}
+	for _, settings := range invalid {
+		func() {
+			defer testForPanic(t)
+			New("test").Using(settings)
+		}()
+	}
+}
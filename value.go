@@ -1,7 +1,12 @@
 package congo
 
 import (
+	"encoding"
+	"errors"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -228,6 +233,483 @@ func (d *durationValue) Get() interface{} { return time.Duration(*d) }
 
 func (d *durationValue) String() string { return (*time.Duration)(d).String() }
 
+// splitList splits s into its trimmed elements separated by sep.
+// An empty string results in an empty list.
+func splitList(s string, sep string) []string {
+
+	if strings.TrimSpace(s) == "" {
+
+		return []string{}
+
+	}
+
+	elements := strings.Split(s, sep)
+
+	for i, e := range elements {
+
+		elements[i] = strings.TrimSpace(e)
+
+	}
+
+	return elements
+
+}
+
+// -- []string Value
+
+type stringSliceValue struct {
+	p   *[]string
+	sep string
+}
+
+func newStringSliceValue(val []string, p *[]string, sep string) Value {
+
+	*p = val
+
+	return &stringSliceValue{p, sep}
+
+}
+
+func (s *stringSliceValue) Set(val string) error {
+
+	*s.p = splitList(val, s.sep)
+
+	return nil
+
+}
+
+func (s *stringSliceValue) Append(val string) error {
+
+	// Limiting the capacity makes append copy the elements, so copies
+	// of the slice are never changed.
+	*s.p = append((*s.p)[:len(*s.p):len(*s.p)], splitList(val, s.sep)...)
+
+	return nil
+
+}
+
+func (s *stringSliceValue) Get() interface{} { return *s.p }
+
+func (s *stringSliceValue) String() string {
+
+	if s.p == nil {
+
+		return ""
+
+	}
+
+	return strings.Join(*s.p, s.sep)
+
+}
+
+// -- []int Value
+
+type intSliceValue struct {
+	p   *[]int
+	sep string
+}
+
+func newIntSliceValue(val []int, p *[]int, sep string) Value {
+
+	*p = val
+
+	return &intSliceValue{p, sep}
+
+}
+
+func (i *intSliceValue) parse(s string) ([]int, error) {
+
+	elements := splitList(s, i.sep)
+
+	v := make([]int, len(elements))
+
+	for j, e := range elements {
+
+		n, err := strconv.ParseInt(e, 0, strconv.IntSize)
+
+		if err != nil {
+
+			return nil, err
+
+		}
+
+		v[j] = int(n)
+
+	}
+
+	return v, nil
+
+}
+
+func (i *intSliceValue) Set(s string) error {
+
+	v, err := i.parse(s)
+
+	if err != nil {
+
+		return err
+
+	}
+
+	*i.p = v
+
+	return nil
+
+}
+
+func (i *intSliceValue) Append(s string) error {
+
+	v, err := i.parse(s)
+
+	if err != nil {
+
+		return err
+
+	}
+
+	*i.p = append((*i.p)[:len(*i.p):len(*i.p)], v...)
+
+	return nil
+
+}
+
+func (i *intSliceValue) Get() interface{} { return *i.p }
+
+func (i *intSliceValue) String() string {
+
+	if i.p == nil {
+
+		return ""
+
+	}
+
+	elements := make([]string, len(*i.p))
+
+	for j, n := range *i.p {
+
+		elements[j] = strconv.Itoa(n)
+
+	}
+
+	return strings.Join(elements, i.sep)
+
+}
+
+// -- []time.Duration Value
+
+type durationSliceValue struct {
+	p   *[]time.Duration
+	sep string
+}
+
+func newDurationSliceValue(val []time.Duration, p *[]time.Duration, sep string) Value {
+
+	*p = val
+
+	return &durationSliceValue{p, sep}
+
+}
+
+func (d *durationSliceValue) parse(s string) ([]time.Duration, error) {
+
+	elements := splitList(s, d.sep)
+
+	v := make([]time.Duration, len(elements))
+
+	for i, e := range elements {
+
+		duration, err := time.ParseDuration(e)
+
+		if err != nil {
+
+			return nil, err
+
+		}
+
+		v[i] = duration
+
+	}
+
+	return v, nil
+
+}
+
+func (d *durationSliceValue) Set(s string) error {
+
+	v, err := d.parse(s)
+
+	if err != nil {
+
+		return err
+
+	}
+
+	*d.p = v
+
+	return nil
+
+}
+
+func (d *durationSliceValue) Append(s string) error {
+
+	v, err := d.parse(s)
+
+	if err != nil {
+
+		return err
+
+	}
+
+	*d.p = append((*d.p)[:len(*d.p):len(*d.p)], v...)
+
+	return nil
+
+}
+
+func (d *durationSliceValue) Get() interface{} { return *d.p }
+
+func (d *durationSliceValue) String() string {
+
+	if d.p == nil {
+
+		return ""
+
+	}
+
+	elements := make([]string, len(*d.p))
+
+	for i, duration := range *d.p {
+
+		elements[i] = duration.String()
+
+	}
+
+	return strings.Join(elements, d.sep)
+
+}
+
+// -- map[string]string Value
+
+type stringMapValue struct {
+	p   *map[string]string
+	sep string
+}
+
+func newStringMapValue(val map[string]string, p *map[string]string, sep string) Value {
+
+	*p = val
+
+	return &stringMapValue{p, sep}
+
+}
+
+// parse parses a list of key=value pairs and adds them to into.
+func (m *stringMapValue) parse(s string, into map[string]string) error {
+
+	for _, e := range splitList(s, m.sep) {
+
+		i := strings.Index(e, "=")
+
+		if i < 0 {
+
+			return errors.New("map entries must be of the form key=value")
+
+		}
+
+		into[strings.TrimSpace(e[:i])] = strings.TrimSpace(e[i+1:])
+
+	}
+
+	return nil
+
+}
+
+func (m *stringMapValue) Set(s string) error {
+
+	v := make(map[string]string)
+
+	if err := m.parse(s, v); err != nil {
+
+		return err
+
+	}
+
+	*m.p = v
+
+	return nil
+
+}
+
+func (m *stringMapValue) Append(s string) error {
+
+	v := make(map[string]string, len(*m.p))
+
+	for key, value := range *m.p {
+
+		v[key] = value
+
+	}
+
+	if err := m.parse(s, v); err != nil {
+
+		return err
+
+	}
+
+	*m.p = v
+
+	return nil
+
+}
+
+func (m *stringMapValue) Get() interface{} { return *m.p }
+
+func (m *stringMapValue) String() string {
+
+	if m.p == nil {
+
+		return ""
+
+	}
+
+	keys := make([]string, 0, len(*m.p))
+
+	for key := range *m.p {
+
+		keys = append(keys, key)
+
+	}
+
+	sort.Strings(keys)
+
+	elements := make([]string, len(keys))
+
+	for i, key := range keys {
+
+		elements[i] = key + "=" + (*m.p)[key]
+
+	}
+
+	return strings.Join(elements, m.sep)
+
+}
+
+// -- encoding.TextUnmarshaler Value
+
+type textValue struct {
+	p encoding.TextUnmarshaler
+}
+
+func newTextValue(val encoding.TextMarshaler, p encoding.TextUnmarshaler) Value {
+
+	if val != nil {
+
+		text, err := val.MarshalText()
+
+		if err != nil {
+
+			panic(err)
+
+		}
+
+		if err := p.UnmarshalText(text); err != nil {
+
+			panic(err)
+
+		}
+
+	}
+
+	return &textValue{p}
+
+}
+
+func (v *textValue) Set(s string) error {
+
+	return v.p.UnmarshalText([]byte(s))
+
+}
+
+func (v *textValue) Get() interface{} { return v.p }
+
+func (v *textValue) String() string {
+
+	if v.p == nil {
+
+		return ""
+
+	}
+
+	if m, ok := v.p.(encoding.TextMarshaler); ok {
+
+		if text, err := m.MarshalText(); err == nil {
+
+			return string(text)
+
+		}
+
+	}
+
+	return ""
+
+}
+
+// -- pointer to encoding.TextUnmarshaler Value
+
+// textPointerValue sets a pointer to a new value instead of changing the
+// value it points to, so copies of the pointer aren't affected.
+type textPointerValue struct {
+	p reflect.Value // settable pointer
+}
+
+func newTextPointerValue(p reflect.Value) Value {
+
+	return &textPointerValue{p}
+
+}
+
+func (v *textPointerValue) Set(s string) error {
+
+	n := reflect.New(v.p.Type().Elem())
+
+	if err := n.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+
+		return err
+
+	}
+
+	v.p.Set(n)
+
+	return nil
+
+}
+
+func (v *textPointerValue) Get() interface{} { return v.p.Interface() }
+
+func (v *textPointerValue) String() string {
+
+	if !v.p.IsValid() || v.p.IsNil() {
+
+		return ""
+
+	}
+
+	return (&textValue{v.p.Interface().(encoding.TextUnmarshaler)}).String()
+
+}
+
+// SliceValue is a Value that holds several elements e.g. a slice or a map.
+//
+// Set replaces all elements with the ones given, Append adds them to the
+// existing elements. Sources that provide several values for the same setting
+// (e.g. repeated flags or shadowed keys) call Set for the first and Append
+// for all following values.
+type SliceValue interface {
+	Value
+
+	Append(string) error
+}
+
 // Value is the interface to the dynamic value stored in a settings.
 // (The default value is represented as a string.)
 //